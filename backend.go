@@ -0,0 +1,168 @@
+package bigq
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/bigquery/v2"
+)
+
+// backend performs the requests to the BigQuery API needed by the service
+// and its queries. It can be replaced by a fake implementation to test the
+// query logic without making any request.
+type backend interface {
+	// Query runs the given query request in the given project.
+	Query(ctx context.Context, projectID string, req *bigquery.QueryRequest) (*bigquery.QueryResponse, error)
+
+	// GetJob returns the job with the given ID. The location is optional.
+	GetJob(ctx context.Context, projectID, jobID, location string) (*bigquery.Job, error)
+
+	// GetQueryResults returns the page of results of the given query job
+	// starting at start with up to maxResults rows. If maxResults is 0, the
+	// default max results are returned. The location and the page token are
+	// optional. If the job is not complete yet, BigQuery waits up to the
+	// given timeout for it to complete before responding. If the timeout is
+	// 0, the BigQuery default is used.
+	GetQueryResults(
+		ctx context.Context,
+		projectID, jobID, location string,
+		start, maxResults uint64,
+		pageToken string,
+		timeout time.Duration,
+	) (*bigquery.GetQueryResultsResponse, error)
+
+	// InsertJob inserts the given job in the given project.
+	InsertJob(ctx context.Context, projectID string, job *bigquery.Job) (*bigquery.Job, error)
+
+	// CancelJob requests the cancellation of the job with the given ID. The
+	// location is optional.
+	CancelJob(ctx context.Context, projectID, jobID, location string) (*bigquery.JobCancelResponse, error)
+
+	// GetTable returns the table with the given ID of the given dataset.
+	GetTable(ctx context.Context, projectID, datasetID, tableID string) (*bigquery.Table, error)
+
+	// PatchTable updates the given table with the fields of the given table
+	// that are set.
+	PatchTable(ctx context.Context, projectID, datasetID, tableID string, table *bigquery.Table) (*bigquery.Table, error)
+
+	// ListTables returns the page of tables of the given dataset with the
+	// given page token, which is optional.
+	ListTables(ctx context.Context, projectID, datasetID, pageToken string) (*bigquery.TableList, error)
+
+	// GetDataset returns the dataset with the given ID of the given project.
+	GetDataset(ctx context.Context, projectID, datasetID string) (*bigquery.Dataset, error)
+
+	// ListDatasets returns the page of datasets of the given project with
+	// the given page token, which is optional.
+	ListDatasets(ctx context.Context, projectID, pageToken string) (*bigquery.DatasetList, error)
+
+	// InsertAll streams the rows of the given request into the given table.
+	InsertAll(
+		ctx context.Context,
+		projectID, datasetID, tableID string,
+		req *bigquery.TableDataInsertAllRequest,
+	) (*bigquery.TableDataInsertAllResponse, error)
+}
+
+// serviceBackend is the backend that makes the requests to BigQuery using
+// the client service.
+type serviceBackend struct {
+	service *bigquery.Service
+	// client is the HTTP client the service makes the requests with, if it
+	// is known.
+	client *http.Client
+}
+
+func (b *serviceBackend) Query(ctx context.Context, projectID string, req *bigquery.QueryRequest) (*bigquery.QueryResponse, error) {
+	return b.service.Jobs.Query(projectID, req).Context(ctx).Do()
+}
+
+func (b *serviceBackend) GetJob(ctx context.Context, projectID, jobID, location string) (*bigquery.Job, error) {
+	call := b.service.Jobs.Get(projectID, jobID)
+	if location != "" {
+		call.Location(location)
+	}
+	return call.Context(ctx).Do()
+}
+
+func (b *serviceBackend) GetQueryResults(
+	ctx context.Context,
+	projectID, jobID, location string,
+	start, maxResults uint64,
+	pageToken string,
+	timeout time.Duration,
+) (*bigquery.GetQueryResultsResponse, error) {
+	call := b.service.Jobs.GetQueryResults(projectID, jobID)
+	call.StartIndex(start)
+
+	if location != "" {
+		call.Location(location)
+	}
+
+	if maxResults > 0 {
+		call.MaxResults(int64(maxResults))
+	}
+
+	if pageToken != "" {
+		call.PageToken(pageToken)
+	}
+
+	if timeout > 0 {
+		call.TimeoutMs(int64(timeout / time.Millisecond))
+	}
+
+	return call.Context(ctx).Do()
+}
+
+func (b *serviceBackend) InsertJob(ctx context.Context, projectID string, job *bigquery.Job) (*bigquery.Job, error) {
+	return b.service.Jobs.Insert(projectID, job).Context(ctx).Do()
+}
+
+func (b *serviceBackend) CancelJob(ctx context.Context, projectID, jobID, location string) (*bigquery.JobCancelResponse, error) {
+	call := b.service.Jobs.Cancel(projectID, jobID)
+	if location != "" {
+		call.Location(location)
+	}
+	return call.Context(ctx).Do()
+}
+
+func (b *serviceBackend) GetTable(ctx context.Context, projectID, datasetID, tableID string) (*bigquery.Table, error) {
+	return b.service.Tables.Get(projectID, datasetID, tableID).Context(ctx).Do()
+}
+
+func (b *serviceBackend) PatchTable(
+	ctx context.Context,
+	projectID, datasetID, tableID string,
+	table *bigquery.Table,
+) (*bigquery.Table, error) {
+	return b.service.Tables.Patch(projectID, datasetID, tableID, table).Context(ctx).Do()
+}
+
+func (b *serviceBackend) ListTables(ctx context.Context, projectID, datasetID, pageToken string) (*bigquery.TableList, error) {
+	call := b.service.Tables.List(projectID, datasetID)
+	if pageToken != "" {
+		call.PageToken(pageToken)
+	}
+	return call.Context(ctx).Do()
+}
+
+func (b *serviceBackend) GetDataset(ctx context.Context, projectID, datasetID string) (*bigquery.Dataset, error) {
+	return b.service.Datasets.Get(projectID, datasetID).Context(ctx).Do()
+}
+
+func (b *serviceBackend) ListDatasets(ctx context.Context, projectID, pageToken string) (*bigquery.DatasetList, error) {
+	call := b.service.Datasets.List(projectID)
+	if pageToken != "" {
+		call.PageToken(pageToken)
+	}
+	return call.Context(ctx).Do()
+}
+
+func (b *serviceBackend) InsertAll(
+	ctx context.Context,
+	projectID, datasetID, tableID string,
+	req *bigquery.TableDataInsertAllRequest,
+) (*bigquery.TableDataInsertAllResponse, error) {
+	return b.service.Tabledata.InsertAll(projectID, datasetID, tableID, req).Context(ctx).Do()
+}
@@ -0,0 +1,559 @@
+package bigq
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+)
+
+// fakeBackend is a backend that serves the given rows as the resultset of
+// every query. The jobs of the queries are running until the job or its
+// results have been requested the given number of times.
+type fakeBackend struct {
+	schema   *bigquery.TableSchema
+	rows     []*bigquery.TableRow
+	polls    int
+	jobError *bigquery.ErrorProto
+	// warnings are the errors the jobs complete with, without failing.
+	warnings    []*bigquery.ErrorProto
+	bytesBilled int64
+	// bytesProcessed are the bytes processed by the queries run directly,
+	// including the dry runs.
+	bytesProcessed int64
+	// affectedRows is the number of rows affected by the DML statements.
+	affectedRows int64
+	// queryErr is returned by every request to run a query.
+	queryErr error
+	// pageErrors is the number of requests of pages after the first one
+	// that fail with a transient error before the next one succeeds.
+	pageErrors int
+	// tables are the tables of the datasets, by their dataset and ID, such
+	// as "samples.results".
+	tables map[string]*bigquery.Table
+	// datasets are the IDs of the datasets of the project.
+	datasets []string
+	// forbidden are the IDs of the datasets that can't be accessed.
+	forbidden []string
+	// insertErrors are the errors of the rows streamed into the tables.
+	insertErrors []*bigquery.TableDataInsertAllResponseInsertErrors
+	insertAll    []*bigquery.TableDataInsertAllRequest
+	// sessionID is the ID of the session of the jobs, if any.
+	sessionID string
+
+	requests  []*bigquery.QueryRequest
+	inserted  []*bigquery.Job
+	calls     map[string]int
+	locations []string
+	// timeouts are the timeouts of the requests of the results of the jobs.
+	timeouts []time.Duration
+	// pageTokens are the tokens of the requests of the results of the jobs.
+	pageTokens []string
+	// projects are the projects of the requests of the jobs.
+	projects []string
+}
+
+func newFakeBackend(n int) *fakeBackend {
+	var rows []*bigquery.TableRow
+	for i := 0; i < n; i++ {
+		rows = append(rows, &bigquery.TableRow{F: []*bigquery.TableCell{
+			{V: fmt.Sprint(i)},
+		}})
+	}
+
+	return &fakeBackend{
+		schema: &bigquery.TableSchema{Fields: []*bigquery.TableFieldSchema{
+			{Name: "n", Type: "INTEGER"},
+		}},
+		rows:  rows,
+		calls: make(map[string]int),
+	}
+}
+
+func (b *fakeBackend) Query(ctx context.Context, projectID string, req *bigquery.QueryRequest) (*bigquery.QueryResponse, error) {
+	b.calls["Query"]++
+	b.projects = append(b.projects, projectID)
+	b.requests = append(b.requests, req)
+	if b.queryErr != nil {
+		return nil, b.queryErr
+	}
+
+	ref := &bigquery.JobReference{JobId: "job", ProjectId: projectID}
+	if b.polls > 0 {
+		return &bigquery.QueryResponse{JobReference: ref}, nil
+	}
+
+	page := b.page(0, uint64(req.MaxResults))
+	return &bigquery.QueryResponse{
+		JobComplete:         true,
+		JobReference:        ref,
+		Rows:                page.Rows,
+		Schema:              page.Schema,
+		TotalRows:           page.TotalRows,
+		TotalBytesBilled:    b.bytesBilled,
+		TotalBytesProcessed: b.bytesProcessed,
+		NumDmlAffectedRows:  b.affectedRows,
+		Errors:              page.Errors,
+	}, nil
+}
+
+func (b *fakeBackend) GetJob(ctx context.Context, projectID, jobID, location string) (*bigquery.Job, error) {
+	b.calls["GetJob"]++
+	b.projects = append(b.projects, projectID)
+	b.locations = append(b.locations, location)
+	if b.polls > 0 {
+		b.polls--
+	}
+
+	state := "DONE"
+	if b.polls > 0 {
+		state = "RUNNING"
+	}
+
+	var sessionInfo *bigquery.SessionInfo
+	if b.sessionID != "" {
+		sessionInfo = &bigquery.SessionInfo{SessionId: b.sessionID}
+	}
+
+	return &bigquery.Job{
+		JobReference: &bigquery.JobReference{JobId: jobID, ProjectId: projectID},
+		Status:       &bigquery.JobStatus{State: state, ErrorResult: b.jobError},
+		Configuration: &bigquery.JobConfiguration{Query: &bigquery.JobConfigurationQuery{
+			DestinationTable: &bigquery.TableReference{
+				ProjectId: projectID,
+				DatasetId: "_anon",
+				TableId:   "anon" + jobID,
+			},
+		}},
+		Statistics: &bigquery.JobStatistics{
+			Query: &bigquery.JobStatistics2{
+				TotalBytesBilled:   b.bytesBilled,
+				NumDmlAffectedRows: b.affectedRows,
+			},
+			SessionInfo: sessionInfo,
+		},
+	}, nil
+}
+
+func (b *fakeBackend) GetQueryResults(
+	ctx context.Context,
+	projectID, jobID, location string,
+	start, maxResults uint64,
+	pageToken string,
+	timeout time.Duration,
+) (*bigquery.GetQueryResultsResponse, error) {
+	b.calls["GetQueryResults"]++
+	b.projects = append(b.projects, projectID)
+	b.locations = append(b.locations, location)
+	b.timeouts = append(b.timeouts, timeout)
+	b.pageTokens = append(b.pageTokens, pageToken)
+	ref := &bigquery.JobReference{JobId: jobID, ProjectId: projectID, Location: location}
+	if b.polls > 0 {
+		b.polls--
+	}
+
+	if b.polls > 0 {
+		return &bigquery.GetQueryResultsResponse{JobReference: ref}, nil
+	}
+
+	if b.jobError != nil {
+		return nil, &googleapi.Error{
+			Code:    http.StatusBadRequest,
+			Message: b.jobError.Message,
+			Errors:  []googleapi.ErrorItem{{Reason: b.jobError.Reason, Message: b.jobError.Message}},
+		}
+	}
+
+	if start > 0 && b.pageErrors > 0 {
+		b.pageErrors--
+		return nil, &googleapi.Error{Code: http.StatusServiceUnavailable, Message: "Service unavailable"}
+	}
+
+	page := b.page(start, maxResults)
+	page.JobReference = ref
+	return page, nil
+}
+
+func (b *fakeBackend) InsertJob(ctx context.Context, projectID string, job *bigquery.Job) (*bigquery.Job, error) {
+	b.calls["InsertJob"]++
+	b.projects = append(b.projects, projectID)
+	b.inserted = append(b.inserted, job)
+	return job, nil
+}
+
+func (b *fakeBackend) CancelJob(ctx context.Context, projectID, jobID, location string) (*bigquery.JobCancelResponse, error) {
+	b.calls["CancelJob"]++
+	b.projects = append(b.projects, projectID)
+	b.locations = append(b.locations, location)
+	return &bigquery.JobCancelResponse{Job: &bigquery.Job{
+		Status: &bigquery.JobStatus{State: "DONE"},
+	}}, nil
+}
+
+func (b *fakeBackend) GetTable(ctx context.Context, projectID, datasetID, tableID string) (*bigquery.Table, error) {
+	b.calls["GetTable"]++
+	table, ok := b.tables[datasetID+"."+tableID]
+	if !ok {
+		return nil, &googleapi.Error{Code: http.StatusNotFound, Message: "Not found: Table"}
+	}
+	return table, nil
+}
+
+func (b *fakeBackend) InsertAll(
+	ctx context.Context,
+	projectID, datasetID, tableID string,
+	req *bigquery.TableDataInsertAllRequest,
+) (*bigquery.TableDataInsertAllResponse, error) {
+	b.calls["InsertAll"]++
+	if _, ok := b.tables[datasetID+"."+tableID]; !ok {
+		return nil, &googleapi.Error{Code: http.StatusNotFound, Message: "Not found: Table"}
+	}
+
+	b.insertAll = append(b.insertAll, req)
+	return &bigquery.TableDataInsertAllResponse{InsertErrors: b.insertErrors}, nil
+}
+
+func (b *fakeBackend) PatchTable(
+	ctx context.Context,
+	projectID, datasetID, tableID string,
+	table *bigquery.Table,
+) (*bigquery.Table, error) {
+	b.calls["PatchTable"]++
+	current, ok := b.tables[datasetID+"."+tableID]
+	if !ok {
+		return nil, &googleapi.Error{Code: http.StatusNotFound, Message: "Not found: Table"}
+	}
+
+	if table.ExpirationTime != 0 {
+		current.ExpirationTime = table.ExpirationTime
+	}
+	return current, nil
+}
+
+// fakeListPageSize is the number of tables or datasets in every page of their
+// lists.
+const fakeListPageSize = 2
+
+func (b *fakeBackend) ListTables(ctx context.Context, projectID, datasetID, pageToken string) (*bigquery.TableList, error) {
+	b.calls["ListTables"]++
+	var ids []string
+	for name := range b.tables {
+		if strings.HasPrefix(name, datasetID+".") {
+			ids = append(ids, strings.TrimPrefix(name, datasetID+"."))
+		}
+	}
+	sort.Strings(ids)
+
+	list := new(bigquery.TableList)
+	ids, list.NextPageToken = fakeListPage(ids, pageToken)
+	for _, id := range ids {
+		list.Tables = append(list.Tables, &bigquery.TableListTables{
+			TableReference: &bigquery.TableReference{ProjectId: projectID, DatasetId: datasetID, TableId: id},
+		})
+	}
+	return list, nil
+}
+
+func (b *fakeBackend) GetDataset(ctx context.Context, projectID, datasetID string) (*bigquery.Dataset, error) {
+	b.calls["GetDataset"]++
+	for _, id := range b.forbidden {
+		if id == datasetID {
+			return nil, &googleapi.Error{Code: http.StatusForbidden, Message: "Access Denied: Dataset"}
+		}
+	}
+
+	for _, id := range b.datasets {
+		if id == datasetID {
+			return &bigquery.Dataset{
+				DatasetReference: &bigquery.DatasetReference{ProjectId: projectID, DatasetId: id},
+			}, nil
+		}
+	}
+	return nil, &googleapi.Error{Code: http.StatusNotFound, Message: "Not found: Dataset"}
+}
+
+func (b *fakeBackend) ListDatasets(ctx context.Context, projectID, pageToken string) (*bigquery.DatasetList, error) {
+	b.calls["ListDatasets"]++
+	list := new(bigquery.DatasetList)
+	ids, token := fakeListPage(b.datasets, pageToken)
+	list.NextPageToken = token
+	for _, id := range ids {
+		list.Datasets = append(list.Datasets, &bigquery.DatasetListDatasets{
+			DatasetReference: &bigquery.DatasetReference{ProjectId: projectID, DatasetId: id},
+		})
+	}
+	return list, nil
+}
+
+// fakeListPage returns the page of the given IDs with the given token, which
+// is the index of its first ID, and the token of the next page.
+func fakeListPage(ids []string, pageToken string) ([]string, string) {
+	var start int
+	if pageToken != "" {
+		start, _ = strconv.Atoi(pageToken)
+	}
+
+	end := start + fakeListPageSize
+	if end >= len(ids) {
+		return ids[start:], ""
+	}
+	return ids[start:end], strconv.Itoa(end)
+}
+
+func (b *fakeBackend) page(start, maxResults uint64) *bigquery.GetQueryResultsResponse {
+	end := uint64(len(b.rows))
+	if start > end {
+		start = end
+	}
+
+	if maxResults > 0 && start+maxResults < end {
+		end = start + maxResults
+	}
+
+	var pageToken string
+	if end < uint64(len(b.rows)) {
+		pageToken = fmt.Sprintf("token-%d", end)
+	}
+
+	return &bigquery.GetQueryResultsResponse{
+		JobComplete: true,
+		Rows:        b.rows[start:end],
+		Schema:      b.schema,
+		TotalRows:   uint64(len(b.rows)),
+		Errors:      b.warnings,
+		PageToken:   pageToken,
+	}
+}
+
+func newFakeService(backend *fakeBackend, config Config) *Service {
+	config.ProjectID = "go-bigq"
+	config.DatasetID = "samples"
+	config.PollInterval = time.Millisecond
+	return newWithBackend(config, backend)
+}
+
+func TestServiceQueryBackend(t *testing.T) {
+	cases := []struct {
+		name   string
+		polls  int
+		config Config
+		args   []uint64
+		rows   []interface{}
+		calls  map[string]int
+	}{
+		{
+			"complete",
+			0,
+			Config{},
+			[]uint64{0, 2},
+			[]interface{}{"0", "1", "2", "3", "4"},
+			map[string]int{"Query": 1, "GetQueryResults": 2},
+		},
+		{
+			"offset",
+			0,
+			Config{},
+			[]uint64{3, 2},
+			[]interface{}{"3", "4"},
+			map[string]int{"Query": 1, "GetQueryResults": 1},
+		},
+		{
+			"pending",
+			3,
+			Config{},
+			[]uint64{0, 2},
+			[]interface{}{"0", "1", "2", "3", "4"},
+			map[string]int{"Query": 1, "GetQueryResults": 5},
+		},
+		{
+			"batch",
+			2,
+			Config{Priority: PriorityBatch},
+			nil,
+			[]interface{}{"0", "1", "2", "3", "4"},
+			map[string]int{"InsertJob": 1, "GetQueryResults": 2},
+		},
+	}
+
+	for _, c := range cases {
+		assert := assert.New(t)
+		backend := newFakeBackend(5)
+		backend.polls = c.polls
+		service := newFakeService(backend, c.config)
+
+		var start uint64
+		if len(c.args) > 0 {
+			start = c.args[0]
+		}
+
+		q, err := service.Query(testQuery, c.args...)
+		assert.Nil(err, c.name)
+		assert.Equal(uint64(5), q.TotalRows(), c.name)
+
+		var rows []interface{}
+		for start+uint64(len(rows)) < q.TotalRows() {
+			page, err := q.NextPage()
+			assert.Nil(err, c.name)
+			for _, row := range page {
+				rows = append(rows, row[0])
+			}
+		}
+
+		assert.Equal(c.rows, rows, c.name)
+		assert.Equal(c.calls, backend.calls, c.name)
+	}
+}
+
+func TestServiceQueryBackendJobError(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.polls = 2
+	backend.jobError = &bigquery.ErrorProto{
+		Reason:  "invalidQuery",
+		Message: "Syntax error",
+	}
+	service := newFakeService(backend, Config{})
+
+	_, err := service.Query(testQuery)
+	assert.IsType(&JobError{}, err)
+	assert.Equal("Syntax error", err.Error())
+	assert.Equal(2, backend.calls["GetQueryResults"])
+	assert.Equal(1, backend.calls["GetJob"])
+}
+
+func TestServiceQueryFirstPage(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(3)
+	service := newFakeService(backend, Config{})
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+
+	rows, err := q.NextPage()
+	assert.Nil(err)
+	assert.Equal([][]interface{}{{"0"}, {"1"}, {"2"}}, rows)
+
+	rows, err = q.NextPage()
+	assert.Nil(err)
+	assert.Equal(0, len(rows))
+	assert.Equal(map[string]int{"Query": 1}, backend.calls)
+}
+
+func TestServiceQueryPageRetries(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(4)
+	backend.pageErrors = 2
+	service := newFakeService(backend, Config{
+		DefaultMaxResults: 2,
+		RetryPolicy:       RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond},
+	})
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+
+	rows, err := q.NextPage()
+	assert.Nil(err)
+	assert.Equal([][]interface{}{{"0"}, {"1"}}, rows)
+
+	_, err = q.NextPage()
+	assert.True(IsTransientError(err))
+
+	// the page that failed is requested again
+	rows, err = q.NextPage()
+	assert.Nil(err)
+	assert.Equal([][]interface{}{{"2"}, {"3"}}, rows)
+	assert.Equal(3, backend.calls["GetQueryResults"])
+}
+
+func TestServiceQueryPage(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{DefaultMaxResults: 2})
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+	jobID := q.JobID()
+
+	// the first page has not been retrieved yet
+	token := q.PageToken()
+	q, err = service.QueryPage(jobID, token, 2)
+	assert.Nil(err)
+
+	rows, err := q.NextPage()
+	assert.Nil(err)
+	assert.Equal([][]interface{}{{"0"}, {"1"}}, rows)
+
+	token = q.PageToken()
+	q, err = service.QueryPage(jobID, token, 2)
+	assert.Nil(err)
+	assert.Equal("token-2", backend.pageTokens[len(backend.pageTokens)-1])
+
+	rows, err = q.NextPage()
+	assert.Nil(err)
+	assert.Equal([][]interface{}{{"2"}, {"3"}}, rows)
+
+	rows, err = q.NextPage()
+	assert.Nil(err)
+	assert.Equal([][]interface{}{{"4"}}, rows)
+	assert.Equal("", q.PageToken())
+
+	q, err = service.QueryPage(jobID, "", 3)
+	assert.Nil(err)
+	rows, err = q.NextPage()
+	assert.Nil(err)
+	assert.Len(rows, 3)
+
+	_, err = service.QueryPage(jobID, "not a token", 2)
+	assert.Equal(errInvalidPageToken, err)
+
+	backend.polls = 2
+	_, err = service.QueryPage(jobID, "", 2)
+	assert.Equal(ErrJobNotDone, err)
+}
+
+func TestServiceBillingProject(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.polls = 2
+	service := newFakeService(backend, Config{BillingProjectID: "billing"})
+
+	_, err := service.Query(testQuery)
+	assert.Nil(err)
+	assert.Equal("go-bigq", backend.requests[0].DefaultDataset.ProjectId)
+
+	service.config.Priority = PriorityBatch
+	_, err = service.Query(testQuery)
+	assert.Nil(err)
+
+	job := backend.inserted[0]
+	assert.Equal("billing", job.JobReference.ProjectId)
+	assert.Equal("go-bigq", job.Configuration.Query.DefaultDataset.ProjectId)
+
+	for _, project := range backend.projects {
+		assert.Equal("billing", project)
+	}
+
+	backend.projects = nil
+	_, err = newFakeService(backend, Config{}).Query(testQuery)
+	assert.Nil(err)
+	assert.Equal([]string{"go-bigq"}, backend.projects)
+}
+
+func TestServiceCancelJobBackend(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	service := newFakeService(backend, Config{})
+
+	state, err := service.CancelJob("job")
+	assert.Nil(err)
+	assert.Equal("DONE", state)
+	assert.Equal(1, backend.calls["CancelJob"])
+}
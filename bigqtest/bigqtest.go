@@ -0,0 +1,155 @@
+// Package bigqtest provides helpers to test the queries run with the bigq
+// package, e.g. in the tests of data pipelines.
+package bigqtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TestingT is the part of testing.T used by the helpers, so they can be used
+// with other implementations.
+type TestingT interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// RowsQuerier runs queries and returns all their rows. It is implemented by
+// bigq.Service.
+type RowsQuerier interface {
+	QueryRows(query string) ([]map[string]interface{}, error)
+}
+
+// AssertRows runs the given query and checks that it returns the expected
+// rows, in any order, and reports the rows missing and the unexpected rows
+// otherwise. It returns whether the rows are the expected ones. The values
+// are compared by their string representation, so an INTEGER column with
+// the value 42 matches both int64(42) and "42". Times are compared in UTC
+// with the RFC 3339 format, NUMERIC values as decimals, such as "3.5", and
+// BYTES values as strings.
+func AssertRows(t TestingT, q RowsQuerier, query string, expected []map[string]interface{}) bool {
+	t.Helper()
+
+	rows, err := q.QueryRows(query)
+	if err != nil {
+		t.Errorf("can't run query %q: %s", query, err)
+		return false
+	}
+
+	missing, unexpected := diffRows(expected, rows)
+	if len(missing) == 0 && len(unexpected) == 0 {
+		return true
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "the rows of query %q are not the expected ones", query)
+	if len(missing) > 0 {
+		sb.WriteString("\nmissing rows:")
+		for _, r := range missing {
+			sb.WriteString("\n  " + r)
+		}
+	}
+	if len(unexpected) > 0 {
+		sb.WriteString("\nunexpected rows:")
+		for _, r := range unexpected {
+			sb.WriteString("\n  " + r)
+		}
+	}
+	t.Errorf("%s", sb.String())
+	return false
+}
+
+// diffRows returns the expected rows that are not in the given rows and the
+// rows that are not expected, in their normalized representation. Rows that
+// appear more than once must appear the same number of times in both.
+func diffRows(expected, rows []map[string]interface{}) (missing, unexpected []string) {
+	counts := make(map[string]int)
+	for _, r := range rows {
+		counts[normalizeRow(r)]++
+	}
+
+	for _, r := range expected {
+		key := normalizeRow(r)
+		if counts[key] > 0 {
+			counts[key]--
+			continue
+		}
+		missing = append(missing, key)
+	}
+
+	for key, n := range counts {
+		for i := 0; i < n; i++ {
+			unexpected = append(unexpected, key)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(unexpected)
+	return missing, unexpected
+}
+
+// normalizeRow returns the representation of the given row used to compare
+// it, which is the JSON of the row with all its values normalized.
+func normalizeRow(row map[string]interface{}) string {
+	data, err := json.Marshal(normalize(row))
+	if err != nil {
+		// the normalized values are always strings, maps or slices
+		panic(err)
+	}
+	return string(data)
+}
+
+// normalize returns the given value converted into its string
+// representation, or into a map or slice of normalized values for records
+// and repeated values. NULL values are kept as nil.
+func normalize(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			m[k] = normalize(value)
+		}
+		return m
+	case []map[string]interface{}:
+		s := make([]interface{}, len(v))
+		for i, value := range v {
+			s[i] = normalize(value)
+		}
+		return s
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, value := range v {
+			s[i] = normalize(value)
+		}
+		return s
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case *big.Rat:
+		return trimDecimal(v.FloatString(38))
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// trimDecimal removes the trailing zeros of the fractional part of the given
+// decimal, along with the point if there is no fractional part left.
+func trimDecimal(s string) string {
+	if !strings.Contains(s, ".") {
+		return s
+	}
+	return strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+}
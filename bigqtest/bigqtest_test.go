@@ -0,0 +1,84 @@
+package bigqtest
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeQuerier struct {
+	rows []map[string]interface{}
+	err  error
+}
+
+func (q *fakeQuerier) QueryRows(string) ([]map[string]interface{}, error) {
+	return q.rows, q.err
+}
+
+type fakeT struct {
+	errors []string
+}
+
+func (t *fakeT) Helper() {}
+
+func (t *fakeT) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func TestAssertRows(t *testing.T) {
+	assert := assert.New(t)
+	q := &fakeQuerier{rows: []map[string]interface{}{
+		{"name": "John", "age": int64(42), "score": big.NewRat(7, 2)},
+		{"name": "Jane", "age": nil, "score": big.NewRat(3, 1)},
+		{
+			"name":    "Jack",
+			"age":     int64(21),
+			"created": time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC),
+			"tags":    []interface{}{"a", int64(1)},
+		},
+	}}
+
+	ft := new(fakeT)
+	ok := AssertRows(ft, q, "SELECT * FROM users", []map[string]interface{}{
+		{
+			"name":    "Jack",
+			"age":     "21",
+			"created": "2024-01-01T10:00:00Z",
+			"tags":    []interface{}{"a", "1"},
+		},
+		{"name": "Jane", "age": nil, "score": 3},
+		{"name": "John", "age": 42, "score": "3.5"},
+	})
+	assert.True(ok)
+	assert.Len(ft.errors, 0)
+
+	ft = new(fakeT)
+	ok = AssertRows(ft, q, "SELECT * FROM users", []map[string]interface{}{
+		{"name": "John", "age": 42, "score": 3.5},
+		{"name": "Jane", "age": 30, "score": 3},
+	})
+	assert.False(ok)
+	assert.Len(ft.errors, 1)
+	assert.Contains(ft.errors[0], "missing rows:\n  {\"age\":\"30\",\"name\":\"Jane\",\"score\":\"3\"}")
+	assert.Contains(ft.errors[0], "unexpected rows:\n  {\"age\":\"21\"")
+	assert.Contains(ft.errors[0], "{\"age\":null,\"name\":\"Jane\",\"score\":\"3\"}")
+
+	ft = new(fakeT)
+	q.err = errors.New("invalid query")
+	assert.False(AssertRows(ft, q, "SELECT", nil))
+	assert.Len(ft.errors, 1)
+}
+
+func TestAssertRowsDuplicates(t *testing.T) {
+	assert := assert.New(t)
+	row := map[string]interface{}{"n": int64(1)}
+	q := &fakeQuerier{rows: []map[string]interface{}{row, row}}
+
+	ft := new(fakeT)
+	assert.False(AssertRows(ft, q, "SELECT 1", []map[string]interface{}{{"n": 1}}))
+	assert.True(AssertRows(ft, q, "SELECT 1", []map[string]interface{}{{"n": 1}, {"n": "1"}}))
+}
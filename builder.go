@@ -0,0 +1,139 @@
+package bigq
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// QueryBuilder assembles simple SELECT queries with conditions whose values
+// are bound as named parameters, so they don't need to be escaped. The query
+// and the parameters it builds can be given to QueryWithParams. It is not
+// meant to support every kind of query, just the common ones built
+// dynamically, e.g. from the filters of a request.
+type QueryBuilder struct {
+	columns    []string
+	table      string
+	conditions []string
+	orderBy    []string
+	limit      uint64
+	params     map[string]interface{}
+	err        error
+}
+
+// NewQueryBuilder returns a new builder of a query that selects all the
+// columns of a table.
+func NewQueryBuilder() *QueryBuilder {
+	return &QueryBuilder{params: make(map[string]interface{})}
+}
+
+// Select adds the given columns, or any other expressions, to the columns
+// selected by the query. If no columns are given, all of them are selected.
+func (b *QueryBuilder) Select(cols ...string) *QueryBuilder {
+	b.columns = append(b.columns, cols...)
+	return b
+}
+
+// From sets the table the query selects from, which can be given as
+// "dataset.table" or "project.dataset.table".
+func (b *QueryBuilder) From(table string) *QueryBuilder {
+	b.table = table
+	return b
+}
+
+// Where adds the given condition to the conditions of the query, which must
+// all be true. Every ? in the condition is a placeholder for the argument in
+// the same position, which is bound as a parameter, e.g.
+// Where("age > ? AND country = ?", 18, "ES"). The conditions can't contain
+// any other ?.
+func (b *QueryBuilder) Where(expr string, args ...interface{}) *QueryBuilder {
+	if n := strings.Count(expr, "?"); n != len(args) {
+		b.fail(fmt.Errorf("the condition %q has %d placeholders but %d arguments were given", expr, n, len(args)))
+		return b
+	}
+
+	if b.params == nil {
+		b.params = make(map[string]interface{})
+	}
+
+	var sb strings.Builder
+	for _, arg := range args {
+		i := strings.IndexByte(expr, '?')
+		name := "p" + strconv.Itoa(len(b.params))
+		b.params[name] = arg
+
+		sb.WriteString(expr[:i])
+		sb.WriteString("@" + name)
+		expr = expr[i+1:]
+	}
+	sb.WriteString(expr)
+
+	b.conditions = append(b.conditions, "("+sb.String()+")")
+	return b
+}
+
+// OrderBy adds the given columns to the ordering of the results of the
+// query. Each column can be followed by ASC or DESC, e.g. "created_at DESC".
+func (b *QueryBuilder) OrderBy(cols ...string) *QueryBuilder {
+	b.orderBy = append(b.orderBy, cols...)
+	return b
+}
+
+// Limit sets the maximum number of rows returned by the query. If it is 0,
+// the number of rows is not limited.
+func (b *QueryBuilder) Limit(n uint64) *QueryBuilder {
+	b.limit = n
+	return b
+}
+
+// Build returns the query and the parameters bound to it, which can be given
+// to QueryWithParams. An error is returned if there is no table or any of the
+// conditions has a different number of placeholders and arguments.
+func (b *QueryBuilder) Build() (string, map[string]interface{}, error) {
+	if b.err != nil {
+		return "", nil, b.err
+	}
+
+	if b.table == "" {
+		return "", nil, errors.New("the table of the query can't be empty")
+	}
+
+	if strings.Contains(b.table, "`") {
+		return "", nil, fmt.Errorf("invalid table %q", b.table)
+	}
+
+	columns := "*"
+	if len(b.columns) > 0 {
+		columns = strings.Join(b.columns, ", ")
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "SELECT %s FROM `%s`", columns, b.table)
+
+	if len(b.conditions) > 0 {
+		sb.WriteString(" WHERE " + strings.Join(b.conditions, " AND "))
+	}
+
+	if len(b.orderBy) > 0 {
+		sb.WriteString(" ORDER BY " + strings.Join(b.orderBy, ", "))
+	}
+
+	if b.limit > 0 {
+		fmt.Fprintf(&sb, " LIMIT %d", b.limit)
+	}
+
+	params := make(map[string]interface{}, len(b.params))
+	for name, v := range b.params {
+		params[name] = v
+	}
+	return sb.String(), params, nil
+}
+
+// fail records the given error, which is returned by Build, unless there
+// was already an error.
+func (b *QueryBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
@@ -0,0 +1,62 @@
+package bigq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryBuilder(t *testing.T) {
+	assert := assert.New(t)
+	query, params, err := NewQueryBuilder().
+		Select("id", "name").
+		From("samples.users").
+		Where("age > ? AND country = ?", 18, "ES").
+		Where("active").
+		Where("id IN UNNEST(?)", []int64{1, 2}).
+		OrderBy("created_at DESC", "id").
+		Limit(10).
+		Build()
+	assert.Nil(err)
+	assert.Equal(
+		"SELECT id, name FROM `samples.users` "+
+			"WHERE (age > @p0 AND country = @p1) AND (active) AND (id IN UNNEST(@p2)) "+
+			"ORDER BY created_at DESC, id LIMIT 10",
+		query,
+	)
+	assert.Equal(map[string]interface{}{
+		"p0": 18,
+		"p1": "ES",
+		"p2": []int64{1, 2},
+	}, params)
+
+	query, params, err = NewQueryBuilder().From("samples.users").Build()
+	assert.Nil(err)
+	assert.Equal("SELECT * FROM `samples.users`", query)
+	assert.Len(params, 0)
+}
+
+func TestQueryBuilderZeroValue(t *testing.T) {
+	assert := assert.New(t)
+	var b QueryBuilder
+	query, params, err := b.From("samples.users").Where("age > ?", 18).Build()
+	assert.Nil(err)
+	assert.Equal("SELECT * FROM `samples.users` WHERE (age > @p0)", query)
+	assert.Equal(map[string]interface{}{"p0": 18}, params)
+}
+
+func TestQueryBuilderErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, _, err := NewQueryBuilder().Select("id").Build()
+	assert.NotNil(err)
+
+	_, _, err = NewQueryBuilder().From("samples`; DROP TABLE x; `").Build()
+	assert.NotNil(err)
+
+	_, _, err = NewQueryBuilder().From("samples.users").Where("age > ? AND id = ?", 18).Build()
+	assert.NotNil(err)
+
+	_, _, err = NewQueryBuilder().From("samples.users").Where("age > ?", 18, 21).Build()
+	assert.NotNil(err)
+}
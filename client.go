@@ -1,9 +1,19 @@
 package bigq
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
 	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/google"
 	"golang.org/x/oauth2/jwt"
 	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/impersonate"
+	"google.golang.org/api/option"
 )
 
 // ClientOptions will construct the client service based
@@ -13,6 +23,162 @@ type ClientOptions interface {
 	Service() (*bigquery.Service, error)
 }
 
+// clientOption is implemented by the ClientOptions of this package, so they
+// can be combined with each other.
+type clientOption interface {
+	apply(*clientSettings) error
+}
+
+// clientSettings are the settings used to construct the client service.
+type clientSettings struct {
+	// httpClient is the client used to make the requests. If there are
+	// credentials, it is used as the base of the client that authorizes
+	// the requests.
+	httpClient *http.Client
+	// credentials returns the source of the tokens used to authorize the
+	// requests, with the given scopes or, if there are none, the default
+	// ones of the credentials. The context contains the HTTP client, if any.
+	credentials func(ctx context.Context, scopes []string) (oauth2.TokenSource, error)
+	// scopes are the scopes requested for the tokens.
+	scopes []string
+	// impersonation is the config of the service account impersonated using
+	// the credentials, if any.
+	impersonation *impersonate.CredentialsConfig
+}
+
+var (
+	errMultipleCredentials   = errors.New("only one of the combined client options can provide credentials")
+	errMultipleImpersonation = errors.New("only one of the combined client options can impersonate a service account")
+)
+
+func (s *clientSettings) setCredentials(fn func(ctx context.Context, scopes []string) (oauth2.TokenSource, error)) error {
+	if s.credentials != nil {
+		return errMultipleCredentials
+	}
+
+	s.credentials = fn
+	return nil
+}
+
+// service constructs the client service and returns it along with the HTTP
+// client it makes the requests with, which is nil if it uses a client of its
+// own, that is, if there are no custom client nor credentials.
+func (s *clientSettings) service() (*bigquery.Service, *http.Client, error) {
+	ctx := context.Background()
+	if s.httpClient != nil {
+		ctx = context.WithValue(ctx, oauth2.HTTPClient, s.httpClient)
+	}
+
+	scopes := s.scopes
+	if s.impersonation != nil {
+		// the credentials are only used to impersonate the service account
+		scopes = []string{cloudPlatformScope}
+	}
+
+	client := s.httpClient
+	if s.credentials != nil {
+		ts, err := s.credentials(ctx, scopes)
+		if err != nil {
+			return nil, nil, err
+		}
+		client = s.authorizedClient(ctx, ts)
+	}
+
+	if s.impersonation != nil {
+		// the impersonated tokens are requested with the base credentials,
+		// which are the application default ones if there are no others
+		var opts []option.ClientOption
+		if client != nil {
+			opts = append(opts, option.WithHTTPClient(client))
+		}
+
+		config := *s.impersonation
+		if len(config.Scopes) == 0 {
+			config.Scopes = s.requestScopes()
+		}
+
+		ts, err := impersonate.CredentialsTokenSource(ctx, config, opts...)
+		if err != nil {
+			return nil, nil, err
+		}
+		client = s.authorizedClient(ctx, ts)
+	}
+
+	if client == nil {
+		// use the application default credentials
+		service, err := bigquery.NewService(ctx, option.WithScopes(s.requestScopes()...))
+		return service, nil, err
+	}
+
+	service, err := bigquery.NewService(ctx, option.WithHTTPClient(client))
+	return service, client, err
+}
+
+// requestScopes returns the scopes of the tokens used to authorize the
+// requests, which are the BigQuery scope by default.
+func (s *clientSettings) requestScopes() []string {
+	if len(s.scopes) > 0 {
+		return s.scopes
+	}
+	return []string{bigqueryScope}
+}
+
+// authorizedClient returns a client that authorizes the requests with the
+// tokens of the given source.
+func (s *clientSettings) authorizedClient(ctx context.Context, ts oauth2.TokenSource) *http.Client {
+	client := oauth2.NewClient(ctx, ts)
+	if s.httpClient != nil {
+		client.Timeout = s.httpClient.Timeout
+	}
+	return client
+}
+
+// newService constructs the client service with the given options.
+func newService(opts ...clientOption) (*bigquery.Service, error) {
+	service, _, err := newServiceClient(opts...)
+	return service, err
+}
+
+// newServiceClient constructs the client service with the given options and
+// returns it along with the HTTP client it makes the requests with, if any.
+func newServiceClient(opts ...clientOption) (*bigquery.Service, *http.Client, error) {
+	var settings clientSettings
+	for _, opt := range opts {
+		if err := opt.apply(&settings); err != nil {
+			return nil, nil, err
+		}
+	}
+	return settings.service()
+}
+
+// Combine returns a ClientOptions that will construct the client service
+// using all the given options, e.g. the credentials of a config file with a
+// custom HTTP client. Only the options of this package can be combined and
+// only one of them can provide the credentials.
+func Combine(opts ...ClientOptions) ClientOptions {
+	return combinedOptions(opts)
+}
+
+type combinedOptions []ClientOptions
+
+func (o combinedOptions) apply(settings *clientSettings) error {
+	for _, opt := range o {
+		co, ok := opt.(clientOption)
+		if !ok {
+			return fmt.Errorf("client options of type %T can't be combined", opt)
+		}
+
+		if err := co.apply(settings); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (o combinedOptions) Service() (*bigquery.Service, error) {
+	return newService(o)
+}
+
 // WithConfigFile returns a ClientOptions that will construct the client service
 // using a config file.
 func WithConfigFile(path string) ClientOptions {
@@ -23,13 +189,17 @@ type tokenFileOptions struct {
 	path string
 }
 
-func (o *tokenFileOptions) Service() (*bigquery.Service, error) {
+func (o *tokenFileOptions) apply(settings *clientSettings) error {
 	conf, err := NewJWTConfig(o.path)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return bigquery.New(conf.Client(oauth2.NoContext))
+	return (&jwtTokenOptions{config: conf}).apply(settings)
+}
+
+func (o *tokenFileOptions) Service() (*bigquery.Service, error) {
+	return newService(o)
 }
 
 // WithJWTConfig returns a ClientOptions that will construct the client service
@@ -42,6 +212,182 @@ type jwtTokenOptions struct {
 	config *jwt.Config
 }
 
+func (o *jwtTokenOptions) apply(settings *clientSettings) error {
+	return settings.setCredentials(func(ctx context.Context, scopes []string) (oauth2.TokenSource, error) {
+		config := o.config
+		if len(scopes) > 0 {
+			c := *config
+			c.Scopes = scopes
+			config = &c
+		}
+		return config.TokenSource(ctx), nil
+	})
+}
+
 func (o *jwtTokenOptions) Service() (*bigquery.Service, error) {
-	return bigquery.New(o.config.Client(oauth2.NoContext))
+	return newService(o)
+}
+
+// WithHTTPClient returns a ClientOptions that will construct the client
+// service using the given HTTP client to make the requests, e.g. to use a
+// proxy or custom timeouts. On its own, the client must authorize the
+// requests itself. Combined with options that provide credentials, the
+// client is used as the base of the client that authorizes the requests,
+// as well as to retrieve the tokens.
+func WithHTTPClient(client *http.Client) ClientOptions {
+	return &httpClientOptions{client: client}
+}
+
+type httpClientOptions struct {
+	client *http.Client
+}
+
+func (o *httpClientOptions) apply(settings *clientSettings) error {
+	settings.httpClient = o.client
+	return nil
+}
+
+func (o *httpClientOptions) Service() (*bigquery.Service, error) {
+	return newService(o)
+}
+
+// WithCredentialsJSON returns a ClientOptions that will construct the client
+// service using the given credentials JSON, such as the key of a service
+// account or the credentials of an user, e.g. loaded from a secrets manager.
+func WithCredentialsJSON(data []byte) ClientOptions {
+	return &credentialsOptions{json: data}
+}
+
+// WithCredentialsFile returns a ClientOptions that will construct the client
+// service using the credentials JSON in the given file.
+func WithCredentialsFile(path string) ClientOptions {
+	return &credentialsOptions{path: path}
+}
+
+type credentialsOptions struct {
+	json []byte
+	path string
+}
+
+func (o *credentialsOptions) apply(settings *clientSettings) error {
+	data := o.json
+	if o.path != "" {
+		var err error
+		data, err = ioutil.ReadFile(o.path)
+		if err != nil {
+			return err
+		}
+	}
+
+	var creds struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return fmt.Errorf("invalid credentials JSON: %s", err)
+	}
+
+	if creds.Type == "" {
+		return errors.New("invalid credentials JSON: missing the type of the credentials")
+	}
+
+	return settings.setCredentials(func(ctx context.Context, scopes []string) (oauth2.TokenSource, error) {
+		if len(scopes) == 0 {
+			scopes = []string{bigqueryScope}
+		}
+
+		c, err := google.CredentialsFromJSONWithType(ctx, data, google.CredentialsType(creds.Type), scopes...)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credentials JSON: %s", err)
+		}
+		return c.TokenSource, nil
+	})
+}
+
+func (o *credentialsOptions) Service() (*bigquery.Service, error) {
+	return newService(o)
+}
+
+// WithTokenSource returns a ClientOptions that will construct the client
+// service authorizing the requests with the tokens of the given source, e.g.
+// for non-standard authentication flows such as the workload identity
+// federation with AWS or Azure. The scopes of the tokens are the ones of the
+// source, so the scopes given with WithScopes are ignored. It can't be
+// combined with other options that provide credentials.
+func WithTokenSource(ts oauth2.TokenSource) ClientOptions {
+	return &tokenSourceOptions{ts: ts}
+}
+
+type tokenSourceOptions struct {
+	ts oauth2.TokenSource
+}
+
+func (o *tokenSourceOptions) apply(settings *clientSettings) error {
+	return settings.setCredentials(func(context.Context, []string) (oauth2.TokenSource, error) {
+		return o.ts, nil
+	})
+}
+
+func (o *tokenSourceOptions) Service() (*bigquery.Service, error) {
+	return newService(o)
+}
+
+// WithImpersonation returns a ClientOptions that will construct the client
+// service impersonating the given target service account, which means the
+// requests are authorized with short-lived tokens of the target account
+// instead of the credentials. The credentials are the application default
+// ones, unless it's combined with an option that provides other credentials.
+// The tokens are requested with the given scopes or, if there are none, the
+// scopes given with WithScopes or the BigQuery scope. If delegates are given,
+// the impersonation is done through the chain of delegates, in order, where
+// every service account must be able to impersonate the next one and the last
+// one the target.
+func WithImpersonation(targetSA string, scopes []string, delegates ...string) ClientOptions {
+	return &impersonationOptions{target: targetSA, scopes: scopes, delegates: delegates}
+}
+
+type impersonationOptions struct {
+	target    string
+	scopes    []string
+	delegates []string
+}
+
+func (o *impersonationOptions) apply(settings *clientSettings) error {
+	if settings.impersonation != nil {
+		return errMultipleImpersonation
+	}
+
+	settings.impersonation = &impersonate.CredentialsConfig{
+		TargetPrincipal: o.target,
+		Scopes:          o.scopes,
+		Delegates:       o.delegates,
+	}
+	return nil
+}
+
+func (o *impersonationOptions) Service() (*bigquery.Service, error) {
+	return newService(o)
+}
+
+// WithScopes returns a ClientOptions that will construct the client service
+// requesting the given OAuth scopes for the tokens that authorize the
+// requests, e.g. ReadOnlyScope for workloads that only run queries. It must
+// be combined with other options, unless the application default credentials
+// are used. By default, the BigQuery scope
+// (https://www.googleapis.com/auth/bigquery) is requested, or the scopes of
+// the config given with WithJWTConfig.
+func WithScopes(scopes ...string) ClientOptions {
+	return &scopesOptions{scopes: scopes}
+}
+
+type scopesOptions struct {
+	scopes []string
+}
+
+func (o *scopesOptions) apply(settings *clientSettings) error {
+	settings.scopes = append(settings.scopes, o.scopes...)
+	return nil
+}
+
+func (o *scopesOptions) Service() (*bigquery.Service, error) {
+	return newService(o)
 }
@@ -1,9 +1,22 @@
 package bigq
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/jwt"
+	"google.golang.org/api/bigquery/v2"
 )
 
 func TestTokenFileOptions(t *testing.T) {
@@ -22,3 +35,251 @@ func TestJWTTokenOptions(t *testing.T) {
 	assert.Nil(err)
 	assert.NotNil(service)
 }
+
+// recorder is a RoundTripper that records the requests and answers them
+// with a token for the requests to the token URL and to impersonate a
+// service account, and with an empty JSON object for the rest.
+type recorder struct {
+	requests []*http.Request
+}
+
+const testTokenURL = "https://oauth.test/token"
+
+func (r *recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	r.requests = append(r.requests, req)
+	body := "{}"
+	switch {
+	case req.URL.String() == testTokenURL:
+		body = `{"access_token": "secret", "token_type": "Bearer", "expires_in": 3600}`
+	case req.URL.Host == "iamcredentials.googleapis.com":
+		body = `{"accessToken": "impersonated", "expireTime": "2100-01-01T00:00:00Z"}`
+	}
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+		Request:    req,
+	}, nil
+}
+
+func testJWTConfig(t *testing.T) *jwt.Config {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &jwt.Config{
+		Email: "test@go-bigq.iam.gserviceaccount.com",
+		PrivateKey: pem.EncodeToMemory(&pem.Block{
+			Type:  "RSA PRIVATE KEY",
+			Bytes: x509.MarshalPKCS1PrivateKey(key),
+		}),
+		Scopes:   []string{bigqueryScope},
+		TokenURL: testTokenURL,
+	}
+}
+
+func TestHTTPClientOptions(t *testing.T) {
+	assert := assert.New(t)
+	rec := new(recorder)
+	service, err := WithHTTPClient(&http.Client{Transport: rec}).Service()
+	assert.Nil(err)
+
+	_, err = service.Jobs.Get("go-bigq", "job").Do()
+	assert.Nil(err)
+	assert.Equal(1, len(rec.requests))
+	assert.Equal("", rec.requests[0].Header.Get("Authorization"))
+}
+
+func TestCombineOptions(t *testing.T) {
+	assert := assert.New(t)
+	rec := new(recorder)
+	service, err := Combine(
+		WithJWTConfig(testJWTConfig(t)),
+		WithHTTPClient(&http.Client{Transport: rec}),
+	).Service()
+	assert.Nil(err)
+
+	_, err = service.Jobs.Get("go-bigq", "job").Do()
+	assert.Nil(err)
+	assert.Equal(2, len(rec.requests))
+	assert.Equal(testTokenURL, rec.requests[0].URL.String())
+	assert.Equal("Bearer secret", rec.requests[1].Header.Get("Authorization"))
+}
+
+type customOptions struct{}
+
+func (customOptions) Service() (*bigquery.Service, error) { return nil, nil }
+
+func TestCombineOptionsErrors(t *testing.T) {
+	assert := assert.New(t)
+	conf := testJWTConfig(t)
+
+	_, err := Combine(WithJWTConfig(conf), WithJWTConfig(conf)).Service()
+	assert.Equal(errMultipleCredentials, err)
+
+	_, err = Combine(WithJWTConfig(conf), customOptions{}).Service()
+	assert.NotNil(err)
+}
+
+func testCredentialsJSON(t *testing.T) []byte {
+	conf := testJWTConfig(t)
+	data, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": conf.Email,
+		"private_key":  string(conf.PrivateKey),
+		"token_uri":    testTokenURL,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestCredentialsOptions(t *testing.T) {
+	assert := assert.New(t)
+	data := testCredentialsJSON(t)
+
+	f, err := ioutil.TempFile("", "bigq")
+	assert.Nil(err)
+	defer os.Remove(f.Name())
+	_, err = f.Write(data)
+	assert.Nil(err)
+	assert.Nil(f.Close())
+
+	for _, opts := range []ClientOptions{
+		WithCredentialsJSON(data),
+		WithCredentialsFile(f.Name()),
+	} {
+		rec := new(recorder)
+		service, err := Combine(opts, WithHTTPClient(&http.Client{Transport: rec})).Service()
+		assert.Nil(err)
+
+		_, err = service.Jobs.Get("go-bigq", "job").Do()
+		assert.Nil(err)
+		assert.Equal(2, len(rec.requests))
+		assert.Equal(testTokenURL, rec.requests[0].URL.String())
+		assert.Equal("Bearer secret", rec.requests[1].Header.Get("Authorization"))
+	}
+}
+
+func TestCredentialsOptionsErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := WithCredentialsJSON([]byte(`{"type": `)).Service()
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "invalid credentials JSON")
+
+	_, err = WithCredentialsJSON([]byte(`{}`)).Service()
+	assert.NotNil(err)
+	assert.Contains(err.Error(), "invalid credentials JSON")
+
+	_, err = WithCredentialsFile("/does/not/exist.json").Service()
+	assert.NotNil(err)
+}
+
+func TestTokenSourceOptions(t *testing.T) {
+	assert := assert.New(t)
+	rec := new(recorder)
+	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "federated", TokenType: "Bearer"})
+	service, err := Combine(
+		WithTokenSource(ts),
+		WithHTTPClient(&http.Client{Transport: rec}),
+	).Service()
+	assert.Nil(err)
+
+	_, err = service.Jobs.Get("go-bigq", "job").Do()
+	assert.Nil(err)
+	assert.Equal(1, len(rec.requests))
+	assert.Equal("Bearer federated", rec.requests[0].Header.Get("Authorization"))
+
+	_, err = Combine(WithTokenSource(ts), WithCredentialsJSON(testCredentialsJSON(t))).Service()
+	assert.Equal(errMultipleCredentials, err)
+}
+
+func TestImpersonationOptions(t *testing.T) {
+	assert := assert.New(t)
+	rec := new(recorder)
+	service, err := Combine(
+		WithJWTConfig(testJWTConfig(t)),
+		WithImpersonation("target@go-bigq.iam.gserviceaccount.com", nil, "delegate@go-bigq.iam.gserviceaccount.com"),
+		WithHTTPClient(&http.Client{Transport: rec}),
+	).Service()
+	assert.Nil(err)
+
+	_, err = service.Jobs.Get("go-bigq", "job").Do()
+	assert.Nil(err)
+	assert.Equal(3, len(rec.requests))
+	assert.Equal(testTokenURL, rec.requests[0].URL.String())
+
+	impersonation := rec.requests[1]
+	assert.Contains(impersonation.URL.Path, "target@go-bigq.iam.gserviceaccount.com:generateAccessToken")
+	assert.Equal("Bearer secret", impersonation.Header.Get("Authorization"))
+
+	body, err := ioutil.ReadAll(impersonation.Body)
+	assert.Nil(err)
+	assert.Contains(string(body), "delegate@go-bigq.iam.gserviceaccount.com")
+	assert.Contains(string(body), bigqueryScope)
+
+	assert.Equal("Bearer impersonated", rec.requests[2].Header.Get("Authorization"))
+
+	_, err = Combine(
+		WithImpersonation("target@go-bigq.iam.gserviceaccount.com", nil),
+		WithImpersonation("other@go-bigq.iam.gserviceaccount.com", nil),
+	).Service()
+	assert.Equal(errMultipleImpersonation, err)
+}
+
+// tokenScope returns the scope requested in the given JWT token request.
+func tokenScope(t *testing.T, req *http.Request) string {
+	if err := req.ParseForm(); err != nil {
+		t.Fatal(err)
+	}
+
+	parts := strings.Split(req.PostForm.Get("assertion"), ".")
+	if len(parts) != 3 {
+		t.Fatalf("invalid assertion: %q", req.PostForm.Get("assertion"))
+	}
+
+	claims, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var claimSet struct {
+		Scope string `json:"scope"`
+	}
+	if err := json.Unmarshal(claims, &claimSet); err != nil {
+		t.Fatal(err)
+	}
+	return claimSet.Scope
+}
+
+func TestScopesOptions(t *testing.T) {
+	cases := []struct {
+		opts  ClientOptions
+		scope string
+	}{
+		{WithJWTConfig(testJWTConfig(t)), bigqueryScope},
+		{Combine(WithJWTConfig(testJWTConfig(t)), WithScopes(ReadOnlyScope)), ReadOnlyScope},
+		{WithCredentialsJSON(testCredentialsJSON(t)), bigqueryScope},
+		{Combine(WithCredentialsJSON(testCredentialsJSON(t)), WithScopes(ReadOnlyScope)), ReadOnlyScope},
+		{Combine(
+			WithCredentialsJSON(testCredentialsJSON(t)),
+			WithImpersonation("target@go-bigq.iam.gserviceaccount.com", nil),
+			WithScopes(ReadOnlyScope),
+		), cloudPlatformScope},
+	}
+
+	assert := assert.New(t)
+	for _, c := range cases {
+		rec := new(recorder)
+		service, err := Combine(c.opts, WithHTTPClient(&http.Client{Transport: rec})).Service()
+		assert.Nil(err)
+
+		_, err = service.Jobs.Get("go-bigq", "job").Do()
+		assert.Nil(err)
+		assert.Equal(c.scope, tokenScope(t, rec.requests[0]))
+	}
+}
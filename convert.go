@@ -0,0 +1,285 @@
+package bigq
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/bigquery/v2"
+)
+
+// cellErrorFunc handles the error converting the value of the given column
+// of a row, which is replaced by its zero value instead of failing the
+// conversion of the row.
+type cellErrorFunc func(column string, err error)
+
+// rowMap converts the given row into a map of column names to their values
+// converted to Go types. If onError is not nil, the columns whose values
+// can't be converted are reported to it and set to nil instead of failing.
+func rowMap(schema []*bigquery.TableFieldSchema, row []interface{}, onError cellErrorFunc) (map[string]interface{}, error) {
+	if len(schema) < len(row) {
+		return nil, fmt.Errorf("the schema has %d fields but the row has %d columns", len(schema), len(row))
+	}
+
+	m := make(map[string]interface{}, len(row))
+	for i, cell := range row {
+		v, err := convertValue(schema[i], cell)
+		if err != nil {
+			if onError == nil {
+				return nil, err
+			}
+			onError(schema[i].Name, err)
+			v = nil
+		}
+		m[schema[i].Name] = v
+	}
+	return m, nil
+}
+
+// convertValue converts the value of a cell of the given field into the Go
+// type matching the field type. Values of types without a known conversion
+// are returned as they are. TIMESTAMP values are converted into time.Time in
+// UTC, as are DATE, DATETIME and TIME values, which have no time zone. TIME
+// values have the zero date, that is, January 1, year 0. NUMERIC and
+// BIGNUMERIC values are converted into *big.Rat to keep their precision.
+// GEOGRAPHY values are kept as strings with their WKT representation and
+// BYTES values, which are encoded in base64, are decoded into []byte.
+// RECORD values are converted into maps of field
+// names to their values and REPEATED values into slices of their values, or
+// slices of maps if they are REPEATED RECORD values. NULL values are
+// converted into nil.
+func convertValue(field *bigquery.TableFieldSchema, v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+
+	if field.Mode == "REPEATED" {
+		return convertRepeated(field, v)
+	}
+
+	if isRecord(field) {
+		return convertRecord(field, v)
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("invalid value of type %T for column %q", v, field.Name)
+	}
+
+	var (
+		result interface{}
+		err    error
+	)
+	switch field.Type {
+	case "STRING", "GEOGRAPHY":
+		result = s
+	case "INTEGER", "INT64":
+		result, err = strconv.ParseInt(s, 10, 64)
+	case "FLOAT", "FLOAT64":
+		result, err = strconv.ParseFloat(s, 64)
+	case "BOOLEAN", "BOOL":
+		result, err = strconv.ParseBool(s)
+	case "NUMERIC", "BIGNUMERIC":
+		result, err = parseNumeric(s)
+	case "BYTES":
+		result, err = base64.StdEncoding.DecodeString(s)
+	case "TIMESTAMP":
+		result, err = parseTimestamp(s)
+	case "DATE":
+		result, err = time.Parse(dateFormat, s)
+	case "DATETIME":
+		result, err = parseDatetime(s)
+	case "TIME":
+		result, err = time.Parse(timeFormat, s)
+	default:
+		result = s
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for column %q of type %s: %s", field.Name, field.Type, err)
+	}
+	return result, nil
+}
+
+// Geography is the value of a GEOGRAPHY column in its WKT representation,
+// such as "POINT(-3.7 40.4)". GEOGRAPHY columns can be scanned into
+// Geography or string values.
+type Geography string
+
+var geographyType = reflect.TypeOf(Geography(""))
+
+func isGeography(field *bigquery.TableFieldSchema) bool {
+	return field.Type == "GEOGRAPHY"
+}
+
+// convertRepeated converts the value of a REPEATED cell. It is given as a
+// list of objects with the value of every item in the "v" key.
+func convertRepeated(field *bigquery.TableFieldSchema, v interface{}) (interface{}, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid value of type %T for repeated column %q", v, field.Name)
+	}
+
+	item := *field
+	item.Mode = ""
+
+	values := make([]interface{}, len(items))
+	for i, it := range items {
+		value, err := convertValue(&item, cellValue(it))
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	if !isRecord(field) {
+		return values, nil
+	}
+
+	records := make([]map[string]interface{}, len(values))
+	for i, v := range values {
+		// NULL items are kept as nil maps
+		records[i], _ = v.(map[string]interface{})
+	}
+	return records, nil
+}
+
+// convertRecord converts the value of a RECORD cell. It is given as an object
+// with the list of values of the record fields in the "f" key, each one of
+// them as an object with the value in the "v" key.
+func convertRecord(field *bigquery.TableFieldSchema, v interface{}) (interface{}, error) {
+	obj, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid value of type %T for record column %q", v, field.Name)
+	}
+
+	cells, _ := obj["f"].([]interface{})
+	row := make([]interface{}, len(cells))
+	for i, c := range cells {
+		row[i] = cellValue(c)
+	}
+
+	m, err := rowMap(field.Fields, row, nil)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value for record column %q: %s", field.Name, err)
+	}
+	return m, nil
+}
+
+func isRecord(field *bigquery.TableFieldSchema) bool {
+	return field.Type == "RECORD" || field.Type == "STRUCT"
+}
+
+// cellValue returns the value of a nested cell.
+func cellValue(cell interface{}) interface{} {
+	if obj, ok := cell.(map[string]interface{}); ok {
+		return obj["v"]
+	}
+	return cell
+}
+
+// parseNumeric parses a NUMERIC or BIGNUMERIC value. A big.Rat can represent
+// exactly both the 38 digits of precision of NUMERIC values and the 76 digits
+// of precision of BIGNUMERIC values, so no precision is lost.
+func parseNumeric(s string) (*big.Rat, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid numeric %q", s)
+	}
+	return r, nil
+}
+
+func isNumeric(field *bigquery.TableFieldSchema) bool {
+	return field.Type == "NUMERIC" || field.Type == "BIGNUMERIC"
+}
+
+const (
+	dateFormat     = "2006-01-02"
+	datetimeFormat = "2006-01-02T15:04:05"
+	timeFormat     = "15:04:05"
+)
+
+// parseTimestamp parses a TIMESTAMP value, which is given as a floating point
+// number of seconds since the epoch with microseconds precision, for example,
+// "1.458147217123456E9". The value is parsed as a decimal so no precision is
+// lost.
+func parseTimestamp(s string) (time.Time, error) {
+	secs, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q", s)
+	}
+
+	micros, err := strconv.ParseInt(secs.Mul(secs, big.NewRat(1e6, 1)).FloatString(0), 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q: %s", s, err)
+	}
+
+	whole := micros / 1e6
+	frac := micros % 1e6
+	if frac < 0 {
+		// the fraction of seconds is always positive for time.Unix
+		whole--
+		frac += 1e6
+	}
+	return time.Unix(whole, frac*int64(time.Microsecond)).UTC(), nil
+}
+
+// parseDatetime parses a DATETIME value, which is given as a civil date
+// and time with an optional fraction of seconds, for example,
+// "2016-03-04T05:06:07.123456". The time is returned in UTC.
+func parseDatetime(s string) (time.Time, error) {
+	t, err := time.Parse(datetimeFormat, s)
+	if err != nil {
+		// the date and time may also be separated by a space
+		return time.Parse("2006-01-02 15:04:05", s)
+	}
+	return t, nil
+}
+
+// setValue sets the given value to dst, which must be settable. If dst is a
+// pointer, it's set to a pointer to a copy of the value. If dst implements
+// sql.Scanner, such as sql.NullString, its Scan method is used instead.
+// NULL values can only be set to a sql.Scanner or to a nillable type, such as
+// a pointer, which is set to nil.
+func setValue(dst reflect.Value, v interface{}) error {
+	if dst.CanAddr() {
+		switch d := dst.Addr().Interface().(type) {
+		case sql.Scanner:
+			return d.Scan(v)
+		case *big.Rat:
+			// big.Rat values must not be copied, so they are set instead
+			if r, ok := v.(*big.Rat); ok {
+				d.Set(r)
+				return nil
+			}
+		}
+	}
+
+	if v == nil {
+		switch dst.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		return fmt.Errorf("NULL is not assignable to type %q", dst.Type())
+	}
+
+	val := reflect.ValueOf(v)
+	if dst.Kind() == reflect.Ptr && val.Type().AssignableTo(dst.Type().Elem()) {
+		ptr := reflect.New(dst.Type().Elem())
+		ptr.Elem().Set(val)
+		dst.Set(ptr)
+		return nil
+	}
+
+	if !val.Type().AssignableTo(dst.Type()) {
+		return fmt.Errorf("value of type %q is not assignable to type %q", val.Type(), dst.Type())
+	}
+
+	dst.Set(val)
+	return nil
+}
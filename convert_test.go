@@ -0,0 +1,272 @@
+package bigq
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestConvertValue(t *testing.T) {
+	cases := []struct {
+		typ      string
+		value    interface{}
+		expected interface{}
+	}{
+		{"STRING", "foo", "foo"},
+		{"INTEGER", "42", int64(42)},
+		{"INT64", "-7", int64(-7)},
+		{"FLOAT", "3.45", 3.45},
+		{"FLOAT64", "1e3", 1000.},
+		{"BOOLEAN", "true", true},
+		{"BOOL", "false", false},
+		{"BYTES", "aGk=", []byte("hi")},
+		{"GEOGRAPHY", "POINT(1 2)", "POINT(1 2)"},
+		{"TIMESTAMP", "1.4567E9", time.Unix(1456700000, 0).UTC()},
+		{"UNKNOWN", "foo", "foo"},
+	}
+
+	assert := assert.New(t)
+	for _, c := range cases {
+		field := &bigquery.TableFieldSchema{Name: "foo", Type: c.typ}
+		v, err := convertValue(field, c.value)
+		assert.Nil(err, c.typ)
+		assert.Equal(c.expected, v, c.typ)
+	}
+}
+
+func TestConvertTimeValues(t *testing.T) {
+	cases := []struct {
+		typ      string
+		value    string
+		expected time.Time
+	}{
+		{"TIMESTAMP", "1.458147217123456E9", time.Date(2016, 3, 16, 16, 53, 37, 123456000, time.UTC)},
+		{"TIMESTAMP", "1458147217.000001", time.Date(2016, 3, 16, 16, 53, 37, 1000, time.UTC)},
+		{"TIMESTAMP", "0", time.Unix(0, 0).UTC()},
+		{"TIMESTAMP", "-1.0E-6", time.Date(1969, 12, 31, 23, 59, 59, 999999000, time.UTC)},
+		{"TIMESTAMP", "-86400.5", time.Date(1969, 12, 30, 23, 59, 59, 500000000, time.UTC)},
+		{"TIMESTAMP", "-6.21355968E10", time.Date(1, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"DATE", "2016-03-04", time.Date(2016, 3, 4, 0, 0, 0, 0, time.UTC)},
+		{"DATE", "1900-01-01", time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"DATETIME", "2016-03-04T05:06:07", time.Date(2016, 3, 4, 5, 6, 7, 0, time.UTC)},
+		{"DATETIME", "2016-03-04T05:06:07.123456", time.Date(2016, 3, 4, 5, 6, 7, 123456000, time.UTC)},
+		{"DATETIME", "2016-03-04 05:06:07.5", time.Date(2016, 3, 4, 5, 6, 7, 500000000, time.UTC)},
+		{"TIME", "05:06:07", time.Date(0, 1, 1, 5, 6, 7, 0, time.UTC)},
+		{"TIME", "23:59:59.999999", time.Date(0, 1, 1, 23, 59, 59, 999999000, time.UTC)},
+	}
+
+	assert := assert.New(t)
+	for _, c := range cases {
+		field := &bigquery.TableFieldSchema{Name: "foo", Type: c.typ}
+		v, err := convertValue(field, c.value)
+		assert.Nil(err, c.value)
+		assert.Equal(c.expected, v, c.value)
+	}
+
+	for _, c := range []struct{ typ, value string }{
+		{"TIMESTAMP", "foo"},
+		{"DATE", "2016-03-04T05:06:07"},
+		{"DATETIME", "2016-03-04"},
+		{"TIME", "25:00:00"},
+	} {
+		_, err := convertValue(&bigquery.TableFieldSchema{Name: "foo", Type: c.typ}, c.value)
+		assert.NotNil(err, c.value)
+	}
+}
+
+func TestConvertValueInvalid(t *testing.T) {
+	assert := assert.New(t)
+	_, err := convertValue(&bigquery.TableFieldSchema{Name: "foo", Type: "INTEGER"}, "bar")
+	assert.NotNil(err)
+
+	_, err = convertValue(&bigquery.TableFieldSchema{Name: "foo", Type: "INTEGER"}, 1)
+	assert.NotNil(err)
+}
+
+func TestSetValue(t *testing.T) {
+	assert := assert.New(t)
+	var i int64
+	assert.Nil(setValue(reflect.ValueOf(&i).Elem(), int64(5)))
+	assert.Equal(int64(5), i)
+
+	var v interface{}
+	assert.Nil(setValue(reflect.ValueOf(&v).Elem(), "foo"))
+	assert.Equal("foo", v)
+
+	var p *int64
+	assert.Nil(setValue(reflect.ValueOf(&p).Elem(), int64(5)))
+	assert.Equal(int64(5), *p)
+
+	var r big.Rat
+	assert.Nil(setValue(reflect.ValueOf(&r).Elem(), big.NewRat(1, 2)))
+	assert.Equal("1/2", r.String())
+
+	var s string
+	assert.NotNil(setValue(reflect.ValueOf(&s).Elem(), int64(5)))
+}
+
+func TestConvertNumeric(t *testing.T) {
+	assert := assert.New(t)
+	const (
+		numeric    = "12345678901234567890123456789.123456789"
+		bignumeric = "1234567890123456789012345678901234567.12345678901234567890123456789012345678"
+	)
+
+	v, err := convertValue(&bigquery.TableFieldSchema{Name: "foo", Type: "NUMERIC"}, numeric)
+	assert.Nil(err)
+	assert.Equal(numeric, v.(*big.Rat).FloatString(9))
+
+	v, err = convertValue(&bigquery.TableFieldSchema{Name: "foo", Type: "BIGNUMERIC"}, bignumeric)
+	assert.Nil(err)
+	assert.Equal(bignumeric, v.(*big.Rat).FloatString(38))
+
+	_, err = convertValue(&bigquery.TableFieldSchema{Name: "foo", Type: "NUMERIC"}, "foo")
+	assert.NotNil(err)
+}
+
+func TestRowMap(t *testing.T) {
+	assert := assert.New(t)
+	schema := []*bigquery.TableFieldSchema{
+		{Name: "name", Type: "STRING"},
+		{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+		{Name: "address", Type: "RECORD", Fields: []*bigquery.TableFieldSchema{
+			{Name: "city", Type: "STRING"},
+			{Name: "zip", Type: "INTEGER"},
+		}},
+	}
+
+	row, err := rowMap(schema, []interface{}{
+		"John",
+		[]interface{}{
+			map[string]interface{}{"v": "a"},
+			map[string]interface{}{"v": "b"},
+		},
+		map[string]interface{}{"f": []interface{}{
+			map[string]interface{}{"v": "Madrid"},
+			map[string]interface{}{"v": "28001"},
+		}},
+	}, nil)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{
+		"name": "John",
+		"tags": []interface{}{"a", "b"},
+		"address": map[string]interface{}{
+			"city": "Madrid",
+			"zip":  int64(28001),
+		},
+	}, row)
+
+	row, err = rowMap(schema, []interface{}{
+		"Jane",
+		[]interface{}{},
+		map[string]interface{}{"f": []interface{}{
+			map[string]interface{}{"v": "Paris"},
+			map[string]interface{}{"v": "75001"},
+		}},
+	}, nil)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{
+		"name": "Jane",
+		"tags": []interface{}{},
+		"address": map[string]interface{}{
+			"city": "Paris",
+			"zip":  int64(75001),
+		},
+	}, row)
+
+	_, err = rowMap(schema[:1], []interface{}{"John", "foo"}, nil)
+	assert.NotNil(err)
+
+	invalid := []interface{}{"John", []interface{}{}, map[string]interface{}{"f": []interface{}{
+		map[string]interface{}{"v": "Paris"},
+		map[string]interface{}{"v": "foo"},
+	}}}
+	_, err = rowMap(schema, invalid, nil)
+	assert.NotNil(err)
+
+	var columns []string
+	row, err = rowMap(schema, invalid, func(column string, err error) {
+		columns = append(columns, column)
+	})
+	assert.Nil(err)
+	assert.Equal([]string{"address"}, columns)
+	assert.Nil(row["address"])
+	assert.Equal("John", row["name"])
+}
+
+func TestRowMapNullable(t *testing.T) {
+	assert := assert.New(t)
+	schema := []*bigquery.TableFieldSchema{
+		{Name: "name", Type: "STRING", Mode: "NULLABLE"},
+		{Name: "age", Type: "INTEGER", Mode: "NULLABLE"},
+		{Name: "address", Type: "RECORD", Mode: "NULLABLE", Fields: []*bigquery.TableFieldSchema{
+			{Name: "city", Type: "STRING", Mode: "NULLABLE"},
+		}},
+	}
+
+	row, err := rowMap(schema, []interface{}{"John", "42", map[string]interface{}{"f": []interface{}{
+		map[string]interface{}{"v": nil},
+	}}}, nil)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{
+		"name":    "John",
+		"age":     int64(42),
+		"address": map[string]interface{}{"city": nil},
+	}, row)
+
+	row, err = rowMap(schema, []interface{}{nil, nil, nil}, nil)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{"name": nil, "age": nil, "address": nil}, row)
+}
+
+func TestRowMapNested(t *testing.T) {
+	assert := assert.New(t)
+	row, err := rowMap(orderSchema, orderRow, nil)
+	assert.Nil(err)
+	assert.Equal(map[string]interface{}{
+		"customer": "John",
+		"orders": []map[string]interface{}{
+			{
+				"id":   int64(1),
+				"tags": []interface{}{"fast", "gift"},
+				"items": []map[string]interface{}{
+					{"sku": "A", "qty": int64(2)},
+					{"sku": "B", "qty": int64(1)},
+				},
+			},
+			{
+				"id":   int64(2),
+				"tags": []interface{}{},
+				"items": []map[string]interface{}{
+					{"sku": "C", "qty": int64(5)},
+				},
+			},
+		},
+		"address": map[string]interface{}{"city": "Madrid"},
+	}, row)
+}
+
+func TestConvertRepeatedRecordNull(t *testing.T) {
+	assert := assert.New(t)
+	field := &bigquery.TableFieldSchema{
+		Name: "items",
+		Type: "RECORD",
+		Mode: "REPEATED",
+		Fields: []*bigquery.TableFieldSchema{
+			{Name: "sku", Type: "STRING"},
+		},
+	}
+
+	v, err := convertValue(field, []interface{}{
+		map[string]interface{}{"v": map[string]interface{}{
+			"f": []interface{}{map[string]interface{}{"v": "A"}},
+		}},
+		map[string]interface{}{"v": nil},
+	})
+	assert.Nil(err)
+	assert.Equal([]map[string]interface{}{{"sku": "A"}, nil}, v)
+}
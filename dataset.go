@@ -0,0 +1,69 @@
+package bigq
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+)
+
+var (
+	// ErrDatasetNotFound is returned when the default dataset does not
+	// exist.
+	ErrDatasetNotFound = errors.New("the dataset does not exist")
+
+	// ErrDatasetAccessDenied is returned when the credentials of the service
+	// don't have permission to read the default dataset.
+	ErrDatasetAccessDenied = errors.New("the credentials don't have permission to read the dataset")
+)
+
+// VerifyDataset checks that the default dataset exists and can be read with
+// the credentials of the service, e.g. to fail fast on startup if the
+// service is misconfigured instead of on the first query. It returns
+// ErrDatasetNotFound if the dataset does not exist and
+// ErrDatasetAccessDenied if it can't be read.
+func (s *Service) VerifyDataset(ctx context.Context) error {
+	err := s.config.RetryPolicy.do(ctx, func() error {
+		_, err := s.backend.GetDataset(ctx, s.datasetProject(), s.config.DatasetID)
+		return err
+	})
+
+	if apiErr, ok := err.(*googleapi.Error); ok {
+		switch apiErr.Code {
+		case http.StatusNotFound:
+			return ErrDatasetNotFound
+		case http.StatusForbidden:
+			return ErrDatasetAccessDenied
+		}
+	}
+	return err
+}
+
+// ListDatasets returns the IDs of all the datasets of the project of the
+// default dataset.
+func (s *Service) ListDatasets() ([]string, error) {
+	ctx := context.Background()
+	var ids []string
+	var pageToken string
+	for {
+		var list *bigquery.DatasetList
+		err := s.config.RetryPolicy.do(ctx, func() (err error) {
+			list, err = s.backend.ListDatasets(ctx, s.datasetProject(), pageToken)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, dataset := range list.Datasets {
+			ids = append(ids, dataset.DatasetReference.DatasetId)
+		}
+
+		if list.NextPageToken == "" {
+			return ids, nil
+		}
+		pageToken = list.NextPageToken
+	}
+}
@@ -0,0 +1,39 @@
+package bigq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceListDatasets(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.datasets = []string{"a", "b", "samples", "z"}
+	service := newFakeService(backend, Config{})
+
+	ids, err := service.ListDatasets()
+	assert.Nil(err)
+	assert.Equal([]string{"a", "b", "samples", "z"}, ids)
+	assert.Equal(2, backend.calls["ListDatasets"])
+
+	backend.datasets = nil
+	ids, err = service.ListDatasets()
+	assert.Nil(err)
+	assert.Equal(0, len(ids))
+}
+
+func TestServiceVerifyDataset(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.datasets = []string{"samples"}
+	backend.forbidden = []string{"secret"}
+	service := newFakeService(backend, Config{})
+	ctx := context.Background()
+
+	assert.Nil(service.VerifyDataset(ctx))
+	assert.Equal(ErrDatasetNotFound, service.WithDataset("missing").VerifyDataset(ctx))
+	assert.Equal(ErrDatasetAccessDenied, service.WithDataset("secret").VerifyDataset(ctx))
+	assert.Equal(3, backend.calls["GetDataset"])
+}
@@ -0,0 +1,100 @@
+package bigq
+
+import (
+	"fmt"
+
+	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+)
+
+// JobError is the error returned when a BigQuery job fails. It contains the
+// details of the failure so the reason can be checked, for example, to react
+// differently to a "quotaExceeded" and a "notFound" error.
+type JobError struct {
+	// Reason is a short code identifying the kind of error.
+	Reason string
+	// Location is where the error occurred, if available.
+	Location string
+	// Message is a human readable description of the error.
+	Message string
+	// Errors are all the errors encountered during the execution of the job.
+	Errors []*bigquery.ErrorProto
+}
+
+func newJobError(status *bigquery.JobStatus) *JobError {
+	return &JobError{
+		Reason:   status.ErrorResult.Reason,
+		Location: status.ErrorResult.Location,
+		Message:  status.ErrorResult.Message,
+		Errors:   status.Errors,
+	}
+}
+
+// newWarningsError returns the error of a job that completed with the given
+// errors, which are not fatal.
+func newWarningsError(errs []*bigquery.ErrorProto) *JobError {
+	return &JobError{
+		Reason:   errs[0].Reason,
+		Location: errs[0].Location,
+		Message:  errs[0].Message,
+		Errors:   errs,
+	}
+}
+
+// Error returns the message of the job error.
+func (e *JobError) Error() string {
+	return e.Message
+}
+
+const bytesBilledLimitReason = "bytesBilledLimitExceeded"
+
+// BytesBilledLimitError is the error returned when a query fails because it
+// would bill more bytes than the MaxBytesBilled of the config. BigQuery fails
+// these queries before running them, so they are not billed.
+type BytesBilledLimitError struct {
+	// MaxBytesBilled is the limit of bytes billed the query exceeded.
+	MaxBytesBilled int64
+	// Message is the description of the error given by BigQuery.
+	Message string
+}
+
+// Error returns the message of the error.
+func (e *BytesBilledLimitError) Error() string {
+	return e.Message
+}
+
+// bytesBilledLimitError returns a BytesBilledLimitError if the given error
+// is a failure of a query that exceeded the limit of bytes billed, or the
+// same error otherwise.
+func bytesBilledLimitError(err error, limit int64) error {
+	switch e := err.(type) {
+	case *JobError:
+		if e.Reason == bytesBilledLimitReason {
+			return &BytesBilledLimitError{MaxBytesBilled: limit, Message: e.Message}
+		}
+	case *googleapi.Error:
+		for _, item := range e.Errors {
+			if item.Reason == bytesBilledLimitReason {
+				return &BytesBilledLimitError{MaxBytesBilled: limit, Message: e.Message}
+			}
+		}
+	}
+	return err
+}
+
+// ConversionError is the error of a value of the resultset of a query that
+// could not be converted, which is recorded instead of failing with lenient
+// conversion.
+type ConversionError struct {
+	// Row is the index of the row in the resultset.
+	Row uint64
+	// Column is the name of the column of the value.
+	Column string
+	// Err is the error converting the value.
+	Err error
+}
+
+// Error returns the message of the error with its row and column.
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("row %d: column %q: %s", e.Row, e.Column, e.Err)
+}
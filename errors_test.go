@@ -0,0 +1,63 @@
+package bigq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestNewJobError(t *testing.T) {
+	assert := assert.New(t)
+	errs := []*bigquery.ErrorProto{
+		{Reason: "quotaExceeded", Message: "Quota exceeded"},
+		{Reason: "backendError", Message: "Backend error"},
+	}
+
+	err := newJobError(&bigquery.JobStatus{
+		State:       "DONE",
+		ErrorResult: errs[0],
+		Errors:      errs,
+	})
+	assert.Equal("quotaExceeded", err.Reason)
+	assert.Equal("Quota exceeded", err.Message)
+	assert.Equal("Quota exceeded", err.Error())
+	assert.Equal(errs, err.Errors)
+}
+
+func TestBytesBilledLimitError(t *testing.T) {
+	assert := assert.New(t)
+
+	err := bytesBilledLimitError(&JobError{
+		Reason:  "bytesBilledLimitExceeded",
+		Message: "Query exceeded limit for bytes billed",
+	}, 1000)
+	assert.Equal(&BytesBilledLimitError{
+		MaxBytesBilled: 1000,
+		Message:        "Query exceeded limit for bytes billed",
+	}, err)
+
+	err = bytesBilledLimitError(apiError(400, "bytesBilledLimitExceeded"), 1000)
+	assert.IsType(&BytesBilledLimitError{}, err)
+
+	jobErr := &JobError{Reason: "invalidQuery"}
+	assert.Equal(jobErr, bytesBilledLimitError(jobErr, 1000))
+}
+
+func TestServiceQueryMaxBytesBilled(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.polls = 1
+	backend.jobError = &bigquery.ErrorProto{
+		Reason:  "bytesBilledLimitExceeded",
+		Message: "Query exceeded limit for bytes billed",
+	}
+	service := newFakeService(backend, Config{MaxBytesBilled: 1000})
+
+	_, err := service.Query(testQuery)
+	assert.IsType(&BytesBilledLimitError{}, err)
+	assert.Equal(int64(1000), backend.requests[0].MaximumBytesBilled)
+
+	config := queryJobConfiguration(service.newQueryRequest(testQuery), PriorityBatch)
+	assert.Equal(int64(1000), config.Query.MaximumBytesBilled)
+}
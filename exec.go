@@ -0,0 +1,68 @@
+package bigq
+
+import (
+	"context"
+	"time"
+)
+
+// ExecResult is the result of a statement that does not return rows.
+type ExecResult struct {
+	// JobID is the ID of the BigQuery job that ran the statement.
+	JobID string
+	// StatementType is the type of the statement, such as "INSERT",
+	// "UPDATE" or "CREATE_TABLE".
+	StatementType string
+	// AffectedRows is the number of rows inserted, updated or deleted by
+	// a DML statement. It is always 0 for DDL statements.
+	AffectedRows int64
+}
+
+// Execute runs the given DML or DDL statement, such as an INSERT, UPDATE,
+// DELETE or CREATE TABLE, waits for its job to finish and returns its result.
+// Unlike Query, the statement is not expected to return any rows.
+func (s *Service) Execute(statement string) (*ExecResult, error) {
+	ctx := context.Background()
+	req := s.newQueryRequest(statement)
+
+	if err := s.acquireSlot(ctx); err != nil {
+		return nil, err
+	}
+	defer s.releaseSlot()
+
+	var result *ExecResult
+	err := s.observe(func(submitted time.Time) error {
+		jobID, resp, err := s.submitQuery(ctx, submitted, req, nil, "")
+		if err != nil {
+			return err
+		}
+
+		if resp != nil && resp.JobComplete {
+			s.queryDone(ctx, submitted, resp)
+			result = &ExecResult{
+				JobID:         jobID,
+				StatementType: resp.StatementType,
+				AffectedRows:  resp.NumDmlAffectedRows,
+			}
+			return nil
+		}
+
+		waitCtx, cancel := s.queryDeadline(ctx, submitted)
+		defer cancel()
+
+		job, err := s.waitForJob(waitCtx, submitted, jobID)
+		if err != nil {
+			if s.queryTimedOut(ctx, waitCtx, jobID) {
+				return ErrQueryTimeout
+			}
+			return err
+		}
+
+		result = &ExecResult{JobID: jobID}
+		if job.Statistics != nil && job.Statistics.Query != nil {
+			result.StatementType = job.Statistics.Query.StatementType
+			result.AffectedRows = job.Statistics.Query.NumDmlAffectedRows
+		}
+		return nil
+	})
+	return result, err
+}
@@ -0,0 +1,44 @@
+package bigq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestServiceExecute(t *testing.T) {
+	cases := []struct {
+		name  string
+		polls int
+	}{
+		{"complete", 0},
+		{"pending", 2},
+	}
+
+	for _, c := range cases {
+		assert := assert.New(t)
+		backend := newFakeBackend(0)
+		backend.polls = c.polls
+		backend.affectedRows = 3
+		service := newFakeService(backend, Config{})
+
+		result, err := service.Execute("DELETE FROM foo WHERE true")
+		assert.Nil(err, c.name)
+		assert.Equal("job", result.JobID, c.name)
+		assert.Equal(int64(3), result.AffectedRows, c.name)
+		assert.Equal(0, backend.calls["GetQueryResults"], c.name)
+	}
+}
+
+func TestServiceExecuteJobError(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.polls = 1
+	backend.jobError = &bigquery.ErrorProto{Reason: "invalidQuery", Message: "Syntax error"}
+	service := newFakeService(backend, Config{})
+
+	result, err := service.Execute("DELETE foo")
+	assert.Nil(result)
+	assert.IsType(&JobError{}, err)
+}
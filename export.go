@@ -0,0 +1,89 @@
+package bigq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/api/bigquery/v2"
+)
+
+// Formats of the files tables are exported to.
+const (
+	// FormatCSV is the CSV format. Tables with nested or repeated columns
+	// can't be exported to CSV.
+	FormatCSV = "CSV"
+	// FormatJSON is the newline-delimited JSON format, with a JSON object
+	// per row.
+	FormatJSON = "NEWLINE_DELIMITED_JSON"
+	// FormatAvro is the Avro format.
+	FormatAvro = "AVRO"
+)
+
+// ExportOption is an option of the export of a table.
+type ExportOption interface {
+	applyExtract(*bigquery.JobConfigurationExtract)
+}
+
+// Compression is the compression of the files tables are exported to.
+type Compression string
+
+const (
+	// CompressionNone does not compress the files. It is the default.
+	CompressionNone Compression = "NONE"
+	// CompressionGzip compresses the files with gzip. It can't be used with
+	// the Avro format.
+	CompressionGzip Compression = "GZIP"
+	// CompressionDeflate compresses the files with deflate. It can only be
+	// used with the Avro format.
+	CompressionDeflate Compression = "DEFLATE"
+	// CompressionSnappy compresses the files with snappy. It can only be
+	// used with the Avro format.
+	CompressionSnappy Compression = "SNAPPY"
+)
+
+func (c Compression) applyExtract(config *bigquery.JobConfigurationExtract) {
+	config.Compression = string(c)
+}
+
+// ExportToGCS exports the given table to the given Google Cloud Storage URI,
+// such as "gs://bucket/results.csv", in the given format, which is one of
+// FormatCSV, FormatJSON or FormatAvro, and waits for the export to finish.
+// The table is given the same way as the destination table of QueryToTable.
+// Tables bigger than 1GB must be exported to multiple files using a URI with
+// a wildcard, such as "gs://bucket/results-*.csv". The compression of the
+// files can be given as an option, e.g. CompressionGzip.
+func (s *Service) ExportToGCS(srcTable, gcsURI string, format string, opts ...ExportOption) error {
+	switch format {
+	case FormatCSV, FormatJSON, FormatAvro:
+	default:
+		return fmt.Errorf("invalid export format %q", format)
+	}
+
+	table, err := s.tableReference(srcTable)
+	if err != nil {
+		return err
+	}
+
+	config := &bigquery.JobConfigurationExtract{
+		SourceTable:       table,
+		DestinationUris:   []string{gcsURI},
+		DestinationFormat: format,
+	}
+	for _, opt := range opts {
+		opt.applyExtract(config)
+	}
+
+	ctx := context.Background()
+	submitted := time.Now()
+	job, err := s.insertJob(ctx, &bigquery.JobConfiguration{
+		Extract: config,
+		Labels:  s.config.Labels,
+	}, "")
+	if err != nil {
+		return err
+	}
+
+	_, err = s.waitForJob(ctx, submitted, job.JobReference.JobId)
+	return err
+}
@@ -0,0 +1,41 @@
+package bigq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestServiceExportToGCS(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.polls = 2
+	service := newFakeService(backend, Config{})
+
+	err := service.ExportToGCS("results", "gs://bucket/results-*.csv.gz", FormatCSV, CompressionGzip)
+	assert.Nil(err)
+	assert.Equal(1, backend.calls["InsertJob"])
+	assert.Equal(2, backend.calls["GetJob"])
+
+	config := backend.inserted[0].Configuration.Extract
+	assert.Equal(&bigquery.TableReference{
+		ProjectId: "go-bigq",
+		DatasetId: "samples",
+		TableId:   "results",
+	}, config.SourceTable)
+	assert.Equal([]string{"gs://bucket/results-*.csv.gz"}, config.DestinationUris)
+	assert.Equal("CSV", config.DestinationFormat)
+	assert.Equal("GZIP", config.Compression)
+
+	err = service.ExportToGCS("results", "gs://bucket/results.xml", "XML")
+	assert.NotNil(err)
+
+	err = service.ExportToGCS("a.b.c.d", "gs://bucket/results.json", FormatJSON)
+	assert.NotNil(err)
+	assert.Equal(1, backend.calls["InsertJob"])
+
+	backend.jobError = &bigquery.ErrorProto{Reason: "invalid", Message: "Bucket not found"}
+	err = service.ExportToGCS("results", "gs://missing/results.avro", FormatAvro)
+	assert.IsType(&JobError{}, err)
+}
@@ -0,0 +1,95 @@
+package bigq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"google.golang.org/api/bigquery/v2"
+)
+
+// RowInsertError is the error of a row that could not be inserted.
+type RowInsertError struct {
+	// Index is the index of the row in the rows given to insert.
+	Index int
+	// Errors are the errors of the row. Rows that are valid can also fail
+	// with a "stopped" error, because of the errors of other rows.
+	Errors []*bigquery.ErrorProto
+}
+
+// InsertErrors is returned when some of the rows given to insert could not be
+// inserted, with the errors of every row that failed. The rest of the rows
+// were inserted, unless they failed too.
+type InsertErrors []*RowInsertError
+
+func (e InsertErrors) Error() string {
+	msg := fmt.Sprintf("%d rows could not be inserted", len(e))
+	if len(e) > 0 && len(e[0].Errors) > 0 {
+		msg += fmt.Sprintf(": row %d: %s", e[0].Index, e[0].Errors[0].Message)
+	}
+	return msg
+}
+
+var errInsertIDs = errors.New("there must be an insert ID for every row")
+
+// InsertRows streams the given rows into the given table, which is given the
+// same way as the destination table of QueryToTable, as maps of column names
+// to their values. The rows are available to be queried within a few
+// seconds, without running a load job. Optionally, an insert ID can be given
+// for every row, in order, which BigQuery uses to deduplicate the rows
+// inserted more than once on a best-effort basis. As inserting the same rows
+// twice would duplicate them, the request is only retried with the retry
+// policy if there are insert IDs. If some rows could not be inserted,
+// InsertErrors is returned with their errors.
+func (s *Service) InsertRows(tableID string, rows []map[string]interface{}, insertIDs ...string) error {
+	if len(insertIDs) > 0 && len(insertIDs) != len(rows) {
+		return errInsertIDs
+	}
+
+	ref, err := s.tableReference(tableID)
+	if err != nil {
+		return err
+	}
+
+	req := new(bigquery.TableDataInsertAllRequest)
+	for i, row := range rows {
+		json := make(map[string]bigquery.JsonValue, len(row))
+		for k, v := range row {
+			json[k] = v
+		}
+
+		r := &bigquery.TableDataInsertAllRequestRows{Json: json}
+		if len(insertIDs) > 0 {
+			r.InsertId = insertIDs[i]
+		}
+		req.Rows = append(req.Rows, r)
+	}
+
+	ctx := context.Background()
+	insert := func() (*bigquery.TableDataInsertAllResponse, error) {
+		return s.backend.InsertAll(ctx, ref.ProjectId, ref.DatasetId, ref.TableId, req)
+	}
+
+	var resp *bigquery.TableDataInsertAllResponse
+	if len(insertIDs) > 0 {
+		err = s.config.RetryPolicy.do(ctx, func() (err error) {
+			resp, err = insert()
+			return err
+		})
+	} else {
+		resp, err = insert()
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(resp.InsertErrors) == 0 {
+		return nil
+	}
+
+	errs := make(InsertErrors, len(resp.InsertErrors))
+	for i, e := range resp.InsertErrors {
+		errs[i] = &RowInsertError{Index: int(e.Index), Errors: e.Errors}
+	}
+	return errs
+}
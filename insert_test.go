@@ -0,0 +1,54 @@
+package bigq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestServiceInsertRows(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.tables = map[string]*bigquery.Table{"samples.words": {}}
+	service := newFakeService(backend, Config{})
+
+	err := service.InsertRows("words", []map[string]interface{}{
+		{"word": "zeal", "word_count": 5},
+		{"word": "zed", "word_count": 1},
+	}, "a", "b")
+	assert.Nil(err)
+
+	req := backend.insertAll[0]
+	assert.Equal(2, len(req.Rows))
+	assert.Equal("a", req.Rows[0].InsertId)
+	assert.Equal("b", req.Rows[1].InsertId)
+	assert.Equal(map[string]bigquery.JsonValue{"word": "zeal", "word_count": 5}, req.Rows[0].Json)
+
+	err = service.InsertRows("words", []map[string]interface{}{{"word": "zeal"}}, "a", "b")
+	assert.Equal(errInsertIDs, err)
+
+	err = service.InsertRows("missing", []map[string]interface{}{{"word": "zeal"}})
+	assert.NotNil(err)
+}
+
+func TestServiceInsertRowsErrors(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.tables = map[string]*bigquery.Table{"samples.words": {}}
+	backend.insertErrors = []*bigquery.TableDataInsertAllResponseInsertErrors{
+		{Index: 1, Errors: []*bigquery.ErrorProto{{Reason: "invalid", Message: "no such field: foo"}}},
+	}
+	service := newFakeService(backend, Config{})
+
+	err := service.InsertRows("words", []map[string]interface{}{
+		{"word": "zeal"},
+		{"foo": "bar"},
+	})
+	assert.IsType(InsertErrors{}, err)
+
+	errs := err.(InsertErrors)
+	assert.Equal(1, len(errs))
+	assert.Equal(1, errs[0].Index)
+	assert.Equal("1 rows could not be inserted: row 1: no such field: foo", err.Error())
+}
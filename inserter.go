@@ -0,0 +1,321 @@
+package bigq
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// maxInsertRows and maxInsertBytes are the limits of BigQuery for the
+	// rows streamed in a single request.
+	maxInsertRows  = 50000
+	maxInsertBytes = 10 << 20
+
+	defaultBatchSize     = 500
+	defaultBatchBytes    = 9 << 20
+	defaultFlushInterval = time.Second
+)
+
+// InserterOption is an option of a BufferedInserter.
+type InserterOption func(*inserterOptions)
+
+type inserterOptions struct {
+	batchSize     int
+	batchBytes    int
+	flushInterval time.Duration
+}
+
+// WithBatchSize sets the max number of rows inserted in every batch. By
+// default, it is 500, the number of rows recommended by BigQuery, and it can't
+// be more than 50000.
+func WithBatchSize(n int) InserterOption {
+	return func(o *inserterOptions) {
+		o.batchSize = n
+	}
+}
+
+// WithBatchBytes sets the max size in bytes of the rows, encoded as JSON,
+// inserted in every batch. By default, it is 9MB, which leaves room for the
+// rest of the request under the limit of 10MB of BigQuery.
+func WithBatchBytes(n int) InserterOption {
+	return func(o *inserterOptions) {
+		o.batchBytes = n
+	}
+}
+
+// WithFlushInterval sets the interval the buffered rows are inserted at, even
+// if the batch is not full. If it is 0, the rows are only inserted once the
+// batch is full or they are flushed. By default, it is 1s.
+func WithFlushInterval(d time.Duration) InserterOption {
+	return func(o *inserterOptions) {
+		o.flushInterval = d
+	}
+}
+
+var (
+	errInserterClosed = errors.New("the inserter is closed")
+	errInsertLimits   = fmt.Errorf("the batches can't have more than %d rows nor %d bytes", maxInsertRows, maxInsertBytes)
+)
+
+// BufferedInserter streams rows into a table in batches, which are inserted
+// once they are full or periodically, so the rows don't need to be inserted
+// one by one, e.g. in high-throughput ingestion pipelines. It is safe to use
+// it from multiple goroutines. It must be closed once it's no longer needed
+// to insert the rows still buffered.
+type BufferedInserter struct {
+	s       *Service
+	tableID string
+	opts    inserterOptions
+
+	mu     sync.Mutex
+	rows   []map[string]interface{}
+	size   int
+	errs   []error
+	closed bool
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewInserter returns a BufferedInserter that streams the rows added to it
+// into the given table, which is given the same way as in InsertRows. Every
+// row is given a random insert ID, so the batches that fail are retried with
+// the retry policy without duplicating their rows. The inserter is closed
+// along with the service if it is not closed before, and it can't be created
+// once the service is closed.
+func (s *Service) NewInserter(tableID string, opts ...InserterOption) (*BufferedInserter, error) {
+	if _, err := s.tableReference(tableID); err != nil {
+		return nil, err
+	}
+
+	o := inserterOptions{
+		batchSize:     defaultBatchSize,
+		batchBytes:    defaultBatchBytes,
+		flushInterval: defaultFlushInterval,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.batchSize <= 0 || o.batchSize > maxInsertRows || o.batchBytes <= 0 || o.batchBytes > maxInsertBytes {
+		return nil, errInsertLimits
+	}
+
+	i := &BufferedInserter{
+		s:       s,
+		tableID: tableID,
+		opts:    o,
+		done:    make(chan struct{}),
+	}
+
+	// the inserter is added before its goroutine starts, so it's never left
+	// running if the service is closed in the meantime
+	if err := s.inserters.add(i); err != nil {
+		return nil, err
+	}
+
+	if o.flushInterval > 0 {
+		i.wg.Add(1)
+		go i.flushPeriodically()
+	}
+	return i, nil
+}
+
+// Add buffers the given row, as a map of column names to their values, to be
+// inserted with the next batch. If the batch is full, it is inserted right
+// away and, if it fails, its error can be retrieved with Errors.
+func (i *BufferedInserter) Add(row map[string]interface{}) error {
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+
+	if len(data) > i.opts.batchBytes {
+		return fmt.Errorf("the row has %d bytes, which is more than the %d bytes of a batch", len(data), i.opts.batchBytes)
+	}
+
+	i.mu.Lock()
+	if i.closed {
+		i.mu.Unlock()
+		return errInserterClosed
+	}
+
+	var full []map[string]interface{}
+	if i.size+len(data) > i.opts.batchBytes {
+		full = i.takeRows()
+	}
+
+	i.rows = append(i.rows, row)
+	i.size += len(data)
+	if full == nil && len(i.rows) >= i.opts.batchSize {
+		full = i.takeRows()
+	}
+	i.mu.Unlock()
+
+	if full != nil {
+		i.report(i.insert(full))
+	}
+	return nil
+}
+
+// Flush inserts all the buffered rows right away and returns the error of the
+// insert, which is an InsertErrors if some of the rows could not be inserted.
+func (i *BufferedInserter) Flush() error {
+	i.mu.Lock()
+	rows := i.takeRows()
+	i.mu.Unlock()
+
+	return i.insert(rows)
+}
+
+// Errors returns the errors of the batches inserted in the background, that
+// is, the ones not inserted with Flush, since the last time they were
+// returned. The errors of the rows of a batch are an InsertErrors, whose
+// indexes are the ones of the rows in the batch.
+func (i *BufferedInserter) Errors() []error {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	errs := i.errs
+	i.errs = nil
+	return errs
+}
+
+// Close stops the periodic inserts and inserts the buffered rows, returning
+// the error of the insert the same way as Flush. No rows can be added once it
+// is closed. It is safe to call Close more than once. The inserters are also
+// closed when their service is closed.
+func (i *BufferedInserter) Close() error {
+	i.mu.Lock()
+	if i.closed {
+		i.mu.Unlock()
+		return nil
+	}
+	i.closed = true
+	i.mu.Unlock()
+
+	i.s.inserters.remove(i)
+
+	close(i.done)
+	i.wg.Wait()
+	return i.Flush()
+}
+
+func (i *BufferedInserter) flushPeriodically() {
+	defer i.wg.Done()
+
+	ticker := time.NewTicker(i.opts.flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			i.report(i.Flush())
+		case <-i.done:
+			return
+		}
+	}
+}
+
+// takeRows returns the buffered rows and empties the buffer. The mutex must
+// be held.
+func (i *BufferedInserter) takeRows() []map[string]interface{} {
+	rows := i.rows
+	i.rows = nil
+	i.size = 0
+	return rows
+}
+
+// insert inserts the given rows, giving every row a random insert ID.
+func (i *BufferedInserter) insert(rows []map[string]interface{}) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(rows))
+	for n := range ids {
+		id, err := randomID()
+		if err != nil {
+			return err
+		}
+		ids[n] = id
+	}
+
+	return i.s.InsertRows(i.tableID, rows, ids...)
+}
+
+// report records the given error of a batch inserted in the background, if
+// any.
+func (i *BufferedInserter) report(err error) {
+	if err == nil {
+		return
+	}
+
+	i.mu.Lock()
+	i.errs = append(i.errs, err)
+	i.mu.Unlock()
+}
+
+// openInserters are the inserters of a service and its derived services that
+// are not closed yet.
+type openInserters struct {
+	mu  sync.Mutex
+	all map[*BufferedInserter]struct{}
+	// closed reports whether the inserters were closed along with the
+	// service, so no more inserters can be added.
+	closed bool
+}
+
+// add adds the given inserter, unless the service is closed, in which case
+// ErrServiceClosed is returned.
+func (o *openInserters) add(i *BufferedInserter) error {
+	if o == nil {
+		return nil
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.closed {
+		return ErrServiceClosed
+	}
+	o.all[i] = struct{}{}
+	return nil
+}
+
+func (o *openInserters) remove(i *BufferedInserter) {
+	if o == nil {
+		return
+	}
+
+	o.mu.Lock()
+	delete(o.all, i)
+	o.mu.Unlock()
+}
+
+// closeAll closes all the inserters and returns the error of the first one
+// that fails, if any. No more inserters can be added afterwards.
+func (o *openInserters) closeAll() error {
+	if o == nil {
+		return nil
+	}
+
+	o.mu.Lock()
+	o.closed = true
+	inserters := make([]*BufferedInserter, 0, len(o.all))
+	for i := range o.all {
+		inserters = append(inserters, i)
+	}
+	o.mu.Unlock()
+
+	var firstErr error
+	for _, i := range inserters {
+		if err := i.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,141 @@
+package bigq
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestBufferedInserter(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.tables = map[string]*bigquery.Table{"samples.words": {}}
+	service := newFakeService(backend, Config{})
+
+	inserter, err := service.NewInserter("words", WithBatchSize(2), WithFlushInterval(0))
+	assert.Nil(err)
+
+	assert.Nil(inserter.Add(map[string]interface{}{"word": "zeal"}))
+	assert.Equal(0, backend.calls["InsertAll"])
+
+	assert.Nil(inserter.Add(map[string]interface{}{"word": "zed"}))
+	assert.Equal(1, backend.calls["InsertAll"])
+	assert.Equal(2, len(backend.insertAll[0].Rows))
+	assert.NotEqual("", backend.insertAll[0].Rows[0].InsertId)
+	assert.NotEqual(backend.insertAll[0].Rows[0].InsertId, backend.insertAll[0].Rows[1].InsertId)
+
+	assert.Nil(inserter.Add(map[string]interface{}{"word": "zoo"}))
+	assert.Nil(inserter.Flush())
+	assert.Equal(2, backend.calls["InsertAll"])
+	assert.Equal(1, len(backend.insertAll[1].Rows))
+
+	// there is nothing to insert
+	assert.Nil(inserter.Flush())
+	assert.Nil(inserter.Close())
+	assert.Equal(2, backend.calls["InsertAll"])
+
+	assert.Equal(errInserterClosed, inserter.Add(map[string]interface{}{"word": "zen"}))
+	assert.Nil(inserter.Close())
+}
+
+func TestBufferedInserterBatchBytes(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.tables = map[string]*bigquery.Table{"samples.words": {}}
+	service := newFakeService(backend, Config{})
+
+	inserter, err := service.NewInserter("words", WithBatchBytes(64), WithFlushInterval(0))
+	assert.Nil(err)
+
+	word := strings.Repeat("a", 20)
+	assert.Nil(inserter.Add(map[string]interface{}{"word": word}))
+	assert.Nil(inserter.Add(map[string]interface{}{"word": word}))
+	assert.Equal(0, backend.calls["InsertAll"])
+
+	// the row doesn't fit in the batch, which is inserted before adding it
+	assert.Nil(inserter.Add(map[string]interface{}{"word": word}))
+	assert.Equal(1, backend.calls["InsertAll"])
+	assert.Equal(2, len(backend.insertAll[0].Rows))
+
+	assert.NotNil(inserter.Add(map[string]interface{}{"word": strings.Repeat("a", 64)}))
+	assert.Nil(inserter.Close())
+	assert.Equal(2, backend.calls["InsertAll"])
+}
+
+func TestBufferedInserterPeriodicFlush(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	service := newFakeService(backend, Config{})
+
+	inserter, err := service.NewInserter("missing", WithFlushInterval(time.Millisecond))
+	assert.Nil(err)
+
+	assert.Nil(inserter.Add(map[string]interface{}{"word": "zeal"}))
+	for len(inserter.Errors()) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	assert.Nil(inserter.Close())
+	assert.Equal(1, backend.calls["InsertAll"])
+	assert.Equal(0, len(inserter.Errors()))
+}
+
+func TestServiceNewInserterErrors(t *testing.T) {
+	assert := assert.New(t)
+	service := newFakeService(newFakeBackend(0), Config{})
+
+	_, err := service.NewInserter("a.b.c.d")
+	assert.NotNil(err)
+
+	_, err = service.NewInserter("words", WithBatchSize(maxInsertRows+1))
+	assert.Equal(errInsertLimits, err)
+
+	_, err = service.NewInserter("words", WithBatchBytes(0))
+	assert.Equal(errInsertLimits, err)
+}
+
+func TestServiceCloseInserters(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.tables = map[string]*bigquery.Table{"samples.words": {}, "other.words": {}}
+	service := newFakeService(backend, Config{})
+
+	inserter, err := service.NewInserter("words", WithFlushInterval(time.Hour))
+	assert.Nil(err)
+	derived, err := service.WithDataset("other").NewInserter("words", WithFlushInterval(0))
+	assert.Nil(err)
+	closed, err := service.NewInserter("words")
+	assert.Nil(err)
+	assert.Nil(closed.Close())
+
+	assert.Nil(inserter.Add(map[string]interface{}{"word": "zeal"}))
+	assert.Nil(derived.Add(map[string]interface{}{"word": "zed"}))
+	assert.Equal(0, backend.calls["InsertAll"])
+
+	assert.Nil(service.Close())
+	assert.Equal(2, backend.calls["InsertAll"])
+	assert.Equal(errInserterClosed, inserter.Add(map[string]interface{}{"word": "zen"}))
+	assert.Equal(errInserterClosed, derived.Add(map[string]interface{}{"word": "zen"}))
+
+	_, err = service.NewInserter("words")
+	assert.Equal(ErrServiceClosed, err)
+}
+
+func TestServiceNewInserterClosed(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.tables = map[string]*bigquery.Table{"samples.words": {}}
+	service := newFakeService(backend, Config{})
+	derived := service.WithDataset("other")
+
+	assert.Nil(service.Close())
+
+	_, err := service.NewInserter("words")
+	assert.Equal(ErrServiceClosed, err)
+	_, err = derived.NewInserter("words", WithFlushInterval(0))
+	assert.Equal(ErrServiceClosed, err)
+	assert.Len(service.inserters.all, 0)
+}
@@ -0,0 +1,47 @@
+package bigq
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// QueryInto runs the given query and returns all the rows in its resultset
+// decoded into values of type T, which must be a struct. The columns are set
+// to the fields of every value the same way as in Iter.ScanStruct. If the
+// rows are truncated to the MaxRows of the config, the rows retrieved are
+// returned along with ErrRowsTruncated.
+func QueryInto[T any](s *Service, query string) ([]T, error) {
+	if t := reflect.TypeOf((*T)(nil)).Elem(); t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("can't decode the rows into values of type %s, it must be a struct", t)
+	}
+
+	q, err := s.Query(query)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []T
+	rows := q.All()
+	for {
+		row, ok := rows.Next()
+		if !ok {
+			break
+		}
+
+		var v T
+		if err := scanStruct(s.config.NameMapper, nil, q.Schema(), row, &v); err != nil {
+			return nil, err
+		}
+		result = append(result, v)
+	}
+
+	err = rows.Err()
+	if err == ErrRowsTruncated {
+		return result, err
+	}
+
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
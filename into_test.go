@@ -0,0 +1,67 @@
+package bigq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryInto(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(3)
+	service := newFakeService(backend, Config{DefaultMaxResults: 2})
+
+	type number struct {
+		N int64 `bigquery:"n"`
+	}
+
+	rows, err := QueryInto[number](service, testQuery)
+	assert.Nil(err)
+	assert.Equal([]number{{0}, {1}, {2}}, rows)
+
+	_, err = QueryInto[int64](service, testQuery)
+	assert.NotNil(err)
+	assert.Equal(1, backend.calls["Query"])
+}
+
+func TestQueryIntoMaxRows(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{DefaultMaxResults: 2, MaxRows: 3})
+
+	type number struct {
+		N int64 `bigquery:"n"`
+	}
+
+	rows, err := QueryInto[number](service, testQuery)
+	assert.Equal(ErrRowsTruncated, err)
+	assert.Equal([]number{{0}, {1}, {2}}, rows)
+}
+
+func TestQueryIntoNameMapper(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(2)
+	service := newFakeService(backend, Config{
+		NameMapper: func(field string) string {
+			return SnakeCase(field)[len("num_"):]
+		},
+	})
+
+	type number struct {
+		NumN int64
+	}
+
+	rows, err := QueryInto[number](service, testQuery)
+	assert.Nil(err)
+	assert.Equal([]number{{0}, {1}}, rows)
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+
+	var n number
+	it := q.Iter()
+	assert.True(it.Next(nil))
+	assert.True(it.Next(nil))
+	assert.Nil(it.ScanStruct(&n))
+	assert.Equal(int64(1), n.NumN)
+}
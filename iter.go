@@ -1,6 +1,7 @@
 package bigq
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 )
@@ -17,9 +18,57 @@ type Iter interface {
 	//
 	// The row 1, 2 would result in struct{Foo: 1, Bar:2}
 	// This method returns a boolean reporting if the operation was
-	// successful.
+	// successful. If the given value is nil, it just advances to the next
+	// row, which can be read using Scan.
 	Next(interface{}) bool
 
+	// Scan copies the columns of the current row, that is, the last row
+	// fetched with Next, into the values pointed at by dest, converting them
+	// according to the type of the column. The number of values in dest must
+	// be the same as the number of columns. The supported conversions are:
+	//  STRING     -> *string
+	//  INTEGER    -> *int64
+	//  FLOAT      -> *float64
+	//  BOOLEAN    -> *bool
+	//  TIMESTAMP  -> *time.Time
+	//  DATE       -> *time.Time
+	//  DATETIME   -> *time.Time
+	//  TIME       -> *time.Time
+	//  NUMERIC    -> *big.Rat, *string
+	//  BIGNUMERIC -> *big.Rat, *string
+	//  GEOGRAPHY  -> *Geography, *string
+	//  BYTES      -> *[]byte
+	// All time values are in UTC and NUMERIC and BIGNUMERIC values keep all
+	// their digits of precision. Any column can also be scanned into an
+	// *interface{} and into any sql.Scanner, such as *sql.NullString. NULL
+	// values can only be scanned into pointers to pointers, such as **string,
+	// which are set to nil, into an *interface{} or into a sql.Scanner.
+	Scan(dest ...interface{}) error
+
+	// ScanStruct copies the columns of the current row, that is, the last row
+	// fetched with Next, into the fields of the struct pointed at by dest,
+	// converting them according to the type of the column like in Scan.
+	// Columns are matched to the fields with the same name, which is the one in
+	// the "bigquery" tag of the field or, if it has no tag, the field name or the
+	// name given by the NameMapper of the service, which is the field name in
+	// snake case by default. Names are matched case insensitively. For example,
+	// given:
+	//  struct {
+	//          ID        int64  `bigquery:"user_id"`
+	//          Name      string
+	//          Age       *int64
+	//          CreatedAt time.Time
+	//  }
+	//
+	// The column user_id would be set to ID, name to Name, age to Age and
+	// created_at to CreatedAt.
+	// Pointer and sql.Scanner fields can be used for nullable columns, NULL
+	// values can't be set to other fields. RECORD columns can be set to
+	// struct fields, or pointers to structs, whose fields are matched the same
+	// way, and REPEATED columns to slice fields. Fields tagged with "-",
+	// unexported fields and fields without a matching column are left untouched.
+	ScanStruct(dest interface{}) error
+
 	// Err returns the latest error that happened.
 	Err() error
 }
@@ -29,19 +78,23 @@ type iter struct {
 	rows [][]interface{}
 	idx  int
 	err  error
+	// mapper names the fields of the structs the rows are scanned into.
+	mapper NameMapper
 }
 
 // Next fetches the next row and fills the fields of the given
 // struct pointer with the columns of the row in appearance order.
 // For example, given:
-//  struct {
-//          Foo int
-//          Bar int
-//  }
+//
+//	struct {
+//	        Foo int
+//	        Bar int
+//	}
 //
 // The row 1, 2 would result in struct{Foo: 1, Bar:2}
 // This method returns a boolean reporting if the operation was
-// successful.
+// successful. If the given value is nil, it just advances to the next
+// row, which can be read using Scan.
 func (i *iter) Next(dst interface{}) bool {
 	if i.idx >= len(i.rows) || len(i.rows) == 0 {
 		if err := i.requestNextPage(); err != nil {
@@ -54,9 +107,11 @@ func (i *iter) Next(dst interface{}) bool {
 		}
 	}
 
-	if err := i.scan(dst); err != nil {
-		i.err = err
-		return false
+	if dst != nil {
+		if err := i.scan(dst); err != nil {
+			i.err = err
+			return false
+		}
 	}
 
 	i.idx++
@@ -93,6 +148,12 @@ func (i *iter) scan(dst interface{}) error {
 			continue
 		}
 
+		if row[i-ignored] == nil {
+			// NULL values leave the field with its zero value
+			f.Set(reflect.Zero(f.Type()))
+			continue
+		}
+
 		cell := reflect.ValueOf(row[i-ignored])
 		if !cell.Type().AssignableTo(f.Type()) {
 			return fmt.Errorf("value of type %q is not assignable to field %q of type %q", cell.Type(), field.Name, f.Type())
@@ -103,7 +164,102 @@ func (i *iter) scan(dst interface{}) error {
 	return nil
 }
 
+var errNoCurrentRow = errors.New("there is no current row, Next must be called before Scan")
+
+// Scan copies the columns of the current row, that is, the last row
+// fetched with Next, into the values pointed at by dest, converting them
+// according to the type of the column. The number of values in dest must
+// be the same as the number of columns. The supported conversions are:
+//
+//	STRING     -> *string
+//	INTEGER    -> *int64
+//	FLOAT      -> *float64
+//	BOOLEAN    -> *bool
+//	TIMESTAMP  -> *time.Time
+//	DATE       -> *time.Time
+//	DATETIME   -> *time.Time
+//	TIME       -> *time.Time
+//	NUMERIC    -> *big.Rat, *string
+//	BIGNUMERIC -> *big.Rat, *string
+//	GEOGRAPHY  -> *Geography, *string
+//	BYTES      -> *[]byte
+//
+// All time values are in UTC and NUMERIC and BIGNUMERIC values keep all
+// their digits of precision. Any column can also be scanned into an
+// *interface{} and into any sql.Scanner, such as *sql.NullString. NULL
+// values can only be scanned into pointers to pointers, such as **string,
+// which are set to nil, into an *interface{} or into a sql.Scanner.
+func (i *iter) Scan(dest ...interface{}) error {
+	if i.idx == 0 || i.idx > len(i.rows) {
+		return errNoCurrentRow
+	}
+
+	return scanRow(i.mapper, i.q.(*query).cellErrors(i.idx-1), i.q.Schema(), i.rows[i.idx-1], dest...)
+}
+
+// ScanStruct copies the columns of the current row, that is, the last row
+// fetched with Next, into the fields of the struct pointed at by dest,
+// converting them according to the type of the column like in Scan.
+// Columns are matched to the fields with the same name, which is the one in
+// the "bigquery" tag of the field or, if it has no tag, the field name or the
+// name given by the NameMapper of the service, which is the field name in
+// snake case by default. Names are matched case insensitively. For example,
+// given:
+//
+//	struct {
+//	        ID        int64  `bigquery:"user_id"`
+//	        Name      string
+//	        Age       *int64
+//	        CreatedAt time.Time
+//	}
+//
+// The column user_id would be set to ID, name to Name, age to Age and
+// created_at to CreatedAt.
+// Pointer and sql.Scanner fields can be used for nullable columns, NULL
+// values can't be set to other fields. RECORD columns can be set to
+// struct fields, or pointers to structs, whose fields are matched the same
+// way, and REPEATED columns to slice fields. Fields tagged with "-",
+// unexported fields and fields without a matching column are left untouched.
+func (i *iter) ScanStruct(dest interface{}) error {
+	if i.idx == 0 || i.idx > len(i.rows) {
+		return errNoCurrentRow
+	}
+
+	return scanStruct(i.mapper, i.q.(*query).cellErrors(i.idx-1), i.q.Schema(), i.rows[i.idx-1], dest)
+}
+
 // Err returns the latest error that happened.
 func (i *iter) Err() error {
 	return i.err
 }
+
+// RowIter is a structure to loop through all the rows of a query resultset,
+// regardless of the page they are in.
+type RowIter interface {
+	// Next returns the next row of the resultset and a boolean reporting if
+	// there was a row to return. When it returns false, Err should be checked
+	// to know if there are no more rows or an error happened.
+	Next() ([]interface{}, bool)
+
+	// Err returns the latest error that happened.
+	Err() error
+}
+
+type rowIter struct {
+	it iter
+}
+
+// Next returns the next row of the resultset and a boolean reporting if
+// there was a row to return. When it returns false, Err should be checked
+// to know if there are no more rows or an error happened.
+func (r *rowIter) Next() ([]interface{}, bool) {
+	if !r.it.Next(nil) {
+		return nil, false
+	}
+	return r.it.rows[r.it.idx-1], true
+}
+
+// Err returns the latest error that happened.
+func (r *rowIter) Err() error {
+	return r.it.Err()
+}
@@ -1,15 +1,18 @@
 package bigq
 
 import (
+	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
 )
 
 func TestScan(t *testing.T) {
 	assert := assert.New(t)
 	iter := iterWithRow()
-	var row Row
+	var row iterRow
 	assert.Nil(iter.scan(&row))
 	assert.Equal(row.Num, 1)
 	assert.Equal(row.Float, 3.45)
@@ -17,6 +20,72 @@ func TestScan(t *testing.T) {
 	assert.Equal(row.Bool, true)
 }
 
+func TestIterScan(t *testing.T) {
+	assert := assert.New(t)
+	q := newQuery(context.Background(), nil, &bigquery.GetQueryResultsResponse{
+		JobReference: &bigquery.JobReference{JobId: "foo"},
+		Schema: &bigquery.TableSchema{Fields: []*bigquery.TableFieldSchema{
+			{Name: "str", Type: "STRING"},
+			{Name: "int", Type: "INTEGER"},
+			{Name: "float", Type: "FLOAT"},
+			{Name: "bool", Type: "BOOLEAN"},
+			{Name: "ts", Type: "TIMESTAMP"},
+		}},
+		Rows: []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "hi"}, {V: "1"}, {V: "3.45"}, {V: "true"}, {V: "1.4567E9"}}},
+		},
+	}, "go-bigq", 0, 5)
+
+	it := q.Iter()
+	var (
+		str  string
+		num  int64
+		flt  float64
+		b    bool
+		ts   time.Time
+		anyv interface{}
+	)
+	assert.Equal(errNoCurrentRow, it.Scan(&str, &num, &flt, &b, &ts))
+	assert.True(it.Next(nil))
+	assert.Nil(it.Scan(&str, &num, &flt, &b, &ts))
+	assert.Equal("hi", str)
+	assert.Equal(int64(1), num)
+	assert.Equal(3.45, flt)
+	assert.Equal(true, b)
+	assert.Equal(time.Unix(1456700000, 0).UTC(), ts)
+
+	assert.Nil(it.Scan(&anyv, &num, &flt, &b, &ts))
+	assert.Equal("hi", anyv)
+
+	assert.NotNil(it.Scan(&str, &num))
+	assert.NotNil(it.Scan(&num, &num, &flt, &b, &ts))
+
+	var row struct {
+		Str       string
+		Int       int64
+		Timestamp time.Time `bigquery:"ts"`
+	}
+	assert.Nil(it.ScanStruct(&row))
+	assert.Equal("hi", row.Str)
+	assert.Equal(int64(1), row.Int)
+	assert.Equal(ts, row.Timestamp)
+}
+
+func TestScanNull(t *testing.T) {
+	assert := assert.New(t)
+	iter := &iter{
+		rows: [][]interface{}{
+			[]interface{}{nil, 3.45, nil, true},
+		},
+	}
+	row := iterRow{Num: 1, String: "foo"}
+	assert.Nil(iter.scan(&row))
+	assert.Equal(row.Num, 0)
+	assert.Equal(row.Float, 3.45)
+	assert.Equal(row.String, "")
+	assert.Equal(row.Bool, true)
+}
+
 func TestNext(t *testing.T) {
 	expected := []string{
 		"zwaggered", "zounds", "zone", "zodiacs", "zodiac",
@@ -50,7 +119,7 @@ type Word struct {
 	Word string
 }
 
-type Row struct {
+type iterRow struct {
 	Num    int
 	Float  float64
 	String string
@@ -64,3 +133,31 @@ func iterWithRow() *iter {
 		},
 	}
 }
+
+func TestAll(t *testing.T) {
+	assert := assert.New(t)
+	service, err := New(WithConfigFile(tokenFile), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	})
+	assert.Nil(err)
+
+	q, err := service.Query(testQuery, 0, 5)
+	assert.Nil(err)
+	assert.NotNil(q)
+
+	it := q.All()
+	var words []interface{}
+	for {
+		row, ok := it.Next()
+		if !ok {
+			break
+		}
+		words = append(words, row[0])
+	}
+	assert.Nil(it.Err())
+	assert.Equal(20, len(words))
+
+	_, err = q.NextPage()
+	assert.Equal(errAlreadyReading, err)
+}
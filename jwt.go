@@ -7,7 +7,14 @@ import (
 	"golang.org/x/oauth2/jwt"
 )
 
-const bigqueryScope = "https://www.googleapis.com/auth/bigquery"
+const (
+	bigqueryScope      = "https://www.googleapis.com/auth/bigquery"
+	cloudPlatformScope = "https://www.googleapis.com/auth/cloud-platform"
+
+	// ReadOnlyScope is the OAuth scope that only allows to read BigQuery
+	// data, which is enough to run queries but not to modify any table.
+	ReadOnlyScope = "https://www.googleapis.com/auth/bigquery.readonly"
+)
 
 // NewJWTConfig returns a JWT Configuration with the BigQuery scope
 // and the data in the provided token file.
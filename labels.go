@@ -0,0 +1,86 @@
+package bigq
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	maxLabels      = 64
+	maxLabelLength = 63
+)
+
+// WithLabels returns a copy of the service, using the same connection, that
+// attaches the given labels to the jobs of its queries, in addition to the
+// labels of its config. Labels with the same key replace the ones of the
+// config. Label keys must start with a lowercase letter and, as well as the
+// values, can only contain lowercase letters, digits, underscores and
+// dashes, with up to 63 characters each.
+func (s *Service) WithLabels(labels map[string]string) *Service {
+	svc := *s
+	merged := make(map[string]string, len(s.config.Labels)+len(labels))
+	for k, v := range s.config.Labels {
+		merged[k] = v
+	}
+	for k, v := range labels {
+		merged[k] = v
+	}
+	svc.config.Labels = merged
+	return &svc
+}
+
+// validateLabels returns an error if the given labels are not valid BigQuery
+// job labels. They are checked in order so the error is always the same for
+// the same labels.
+func validateLabels(labels map[string]string) error {
+	if len(labels) > maxLabels {
+		return fmt.Errorf("too many labels: %d, the max is %d", len(labels), maxLabels)
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		if k == "" {
+			return errors.New("invalid label key: it can't be empty")
+		}
+
+		if r, _ := utf8.DecodeRuneInString(k); !isLabelLetter(r) {
+			return fmt.Errorf("invalid label key %q: it must start with a lowercase letter", k)
+		}
+
+		if err := validateLabelPart(k); err != nil {
+			return fmt.Errorf("invalid label key %q: %s", k, err)
+		}
+
+		if err := validateLabelPart(labels[k]); err != nil {
+			return fmt.Errorf("invalid value of label %q: %s", k, err)
+		}
+	}
+	return nil
+}
+
+func validateLabelPart(s string) error {
+	if n := utf8.RuneCountInString(s); n > maxLabelLength {
+		return fmt.Errorf("it has %d characters, the max is %d", n, maxLabelLength)
+	}
+
+	for _, r := range s {
+		if !isLabelLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '-' {
+			return fmt.Errorf("invalid character %q, only lowercase letters, digits, underscores and dashes are allowed", r)
+		}
+	}
+	return nil
+}
+
+// isLabelLetter reports whether the given rune is a letter allowed in labels,
+// that is, a letter that is not uppercase.
+func isLabelLetter(r rune) bool {
+	return unicode.IsLetter(r) && !unicode.IsUpper(r)
+}
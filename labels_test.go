@@ -0,0 +1,72 @@
+package bigq
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceWithLabels(t *testing.T) {
+	assert := assert.New(t)
+	service := &Service{config: Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+		Labels:    map[string]string{"team": "data", "feature": "reports"},
+	}}
+
+	labeled := service.WithLabels(map[string]string{"feature": "export", "env": "prod"})
+	assert.Equal(map[string]string{"team": "data", "feature": "reports"}, service.newQueryRequest(testQuery).Labels)
+	assert.Equal(map[string]string{
+		"team":    "data",
+		"feature": "export",
+		"env":     "prod",
+	}, labeled.newQueryRequest(testQuery).Labels)
+
+	config := queryJobConfiguration(labeled.newQueryRequest(testQuery), PriorityBatch)
+	assert.Equal("export", config.Labels["feature"])
+}
+
+func TestValidateLabels(t *testing.T) {
+	cases := []struct {
+		labels map[string]string
+		ok     bool
+	}{
+		{nil, true},
+		{map[string]string{"team": "data", "cost_center": "1234-a", "empty": ""}, true},
+		{map[string]string{"équipe": "données"}, true},
+		{map[string]string{"": "data"}, false},
+		{map[string]string{"Team": "data"}, false},
+		{map[string]string{"1team": "data"}, false},
+		{map[string]string{"team": "Data"}, false},
+		{map[string]string{"team": "data team"}, false},
+		{map[string]string{"team.name": "data"}, false},
+		{map[string]string{"team": strings.Repeat("a", 64)}, false},
+	}
+
+	assert := assert.New(t)
+	for _, c := range cases {
+		err := validateLabels(c.labels)
+		assert.Equal(c.ok, err == nil, "%v: %v", c.labels, err)
+	}
+
+	labels := make(map[string]string)
+	for i := 0; i < 65; i++ {
+		labels[strings.Repeat("a", i+1)] = ""
+	}
+	assert.NotNil(validateLabels(labels))
+}
+
+func TestServiceQueryInvalidLabels(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{})
+
+	_, err := service.WithLabels(map[string]string{"Team": "data"}).Query(testQuery)
+	assert.NotNil(err)
+	assert.Equal(0, backend.calls["Query"])
+
+	_, err = service.WithLabels(map[string]string{"team": "data"}).Query(testQuery)
+	assert.Nil(err)
+	assert.Equal("data", backend.requests[0].Labels["team"])
+}
@@ -0,0 +1,93 @@
+package bigq
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/api/bigquery/v2"
+)
+
+// LoadOption is an option of the load of files into a table.
+type LoadOption interface {
+	applyLoad(*bigquery.JobConfigurationLoad)
+}
+
+type loadOption func(*bigquery.JobConfigurationLoad)
+
+func (o loadOption) applyLoad(config *bigquery.JobConfigurationLoad) {
+	o(config)
+}
+
+// FormatParquet is the Parquet format, which can only be used to load files.
+const FormatParquet = "PARQUET"
+
+// WithSourceFormat returns a LoadOption that sets the format of the files to
+// load, which is one of FormatCSV, FormatJSON, FormatAvro or FormatParquet.
+// By default, the files are loaded as CSV.
+func WithSourceFormat(format string) LoadOption {
+	return loadOption(func(config *bigquery.JobConfigurationLoad) {
+		config.SourceFormat = format
+	})
+}
+
+// WithAutodetect returns a LoadOption that makes BigQuery infer the schema of
+// the table from the files, if the table does not exist yet, and the options
+// of CSV files, such as their delimiter.
+func WithAutodetect() LoadOption {
+	return loadOption(func(config *bigquery.JobConfigurationLoad) {
+		config.Autodetect = true
+	})
+}
+
+// WithSkipLeadingRows returns a LoadOption that skips the given number of
+// rows at the beginning of every CSV file, such as the row of the headers.
+func WithSkipLeadingRows(rows int64) LoadOption {
+	return loadOption(func(config *bigquery.JobConfigurationLoad) {
+		config.SkipLeadingRows = rows
+	})
+}
+
+func (d WriteDisposition) applyLoad(config *bigquery.JobConfigurationLoad) {
+	config.WriteDisposition = string(d)
+}
+
+func (d CreateDisposition) applyLoad(config *bigquery.JobConfigurationLoad) {
+	config.CreateDisposition = string(d)
+}
+
+// LoadFromGCS loads the files at the given Google Cloud Storage URI, such as
+// "gs://bucket/data-*.csv", into the given table, which is given the same way
+// as the destination table of QueryToTable, and waits for the load to
+// finish. The format of the files, the write and create dispositions of the
+// table and other options of the load can be given as options. If the table
+// has a partition decorator, such as "words$20240101", the files are loaded
+// into that partition, e.g. with WriteTruncate only the partition is
+// replaced. If the load fails, a JobError is returned with all the errors
+// found in the files.
+func (s *Service) LoadFromGCS(destTable, gcsURI string, opts ...LoadOption) error {
+	table, err := s.tableReference(destTable)
+	if err != nil {
+		return err
+	}
+
+	config := &bigquery.JobConfigurationLoad{
+		DestinationTable: table,
+		SourceUris:       []string{gcsURI},
+	}
+	for _, opt := range opts {
+		opt.applyLoad(config)
+	}
+
+	ctx := context.Background()
+	submitted := time.Now()
+	job, err := s.insertJob(ctx, &bigquery.JobConfiguration{
+		Load:   config,
+		Labels: s.config.Labels,
+	}, "")
+	if err != nil {
+		return err
+	}
+
+	_, err = s.waitForJob(ctx, submitted, job.JobReference.JobId)
+	return err
+}
@@ -0,0 +1,56 @@
+package bigq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestServiceLoadFromGCS(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.polls = 2
+	service := newFakeService(backend, Config{})
+
+	err := service.LoadFromGCS(
+		"other.words",
+		"gs://bucket/words-*.csv",
+		WithSourceFormat(FormatCSV),
+		WithAutodetect(),
+		WithSkipLeadingRows(1),
+		WriteAppend,
+		CreateNever,
+	)
+	assert.Nil(err)
+	assert.Equal(1, backend.calls["InsertJob"])
+	assert.Equal(2, backend.calls["GetJob"])
+
+	config := backend.inserted[0].Configuration.Load
+	assert.Equal(&bigquery.TableReference{
+		ProjectId: "go-bigq",
+		DatasetId: "other",
+		TableId:   "words",
+	}, config.DestinationTable)
+	assert.Equal([]string{"gs://bucket/words-*.csv"}, config.SourceUris)
+	assert.Equal("CSV", config.SourceFormat)
+	assert.True(config.Autodetect)
+	assert.Equal(int64(1), config.SkipLeadingRows)
+	assert.Equal("WRITE_APPEND", config.WriteDisposition)
+	assert.Equal("CREATE_NEVER", config.CreateDisposition)
+
+	err = service.LoadFromGCS("a.b.c.d", "gs://bucket/words.csv")
+	assert.NotNil(err)
+
+	err = service.LoadFromGCS("words$20240132", "gs://bucket/words.csv")
+	assert.NotNil(err)
+	assert.Equal(1, backend.calls["InsertJob"])
+
+	err = service.LoadFromGCS("words$20240131", "gs://bucket/words.csv", WriteTruncate)
+	assert.Nil(err)
+	assert.Equal("words$20240131", backend.inserted[1].Configuration.Load.DestinationTable.TableId)
+
+	backend.jobError = &bigquery.ErrorProto{Reason: "invalid", Message: "Error while reading data"}
+	err = service.LoadFromGCS("words", "gs://bucket/words.json", WithSourceFormat(FormatJSON))
+	assert.IsType(&JobError{}, err)
+}
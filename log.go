@@ -0,0 +1,79 @@
+package bigq
+
+import (
+	"context"
+	"time"
+)
+
+// Logger receives the events of the queries run by a service, so they can
+// be logged or inspected while debugging.
+type Logger interface {
+	// LogEvent is called with every event of a query. It's called
+	// synchronously, so it should return quickly.
+	LogEvent(ctx context.Context, event Event)
+}
+
+// LoggerFunc is an adapter to use a function as a Logger.
+type LoggerFunc func(ctx context.Context, event Event)
+
+// LogEvent calls f(ctx, event).
+func (f LoggerFunc) LogEvent(ctx context.Context, event Event) {
+	f(ctx, event)
+}
+
+// EventKind is the kind of an event of a query.
+type EventKind int
+
+const (
+	// EventQuerySubmitted happens when the query has been submitted to
+	// BigQuery and its job has been created.
+	EventQuerySubmitted EventKind = iota
+	// EventPollStarted happens when the job of the query is not complete
+	// and its status starts to be polled.
+	EventPollStarted
+	// EventPoll happens after every check of the status of the job.
+	EventPoll
+	// EventJobDone happens when the job of the query is done, either
+	// because it completed or because it failed.
+	EventJobDone
+)
+
+var eventKindNames = map[EventKind]string{
+	EventQuerySubmitted: "query submitted",
+	EventPollStarted:    "poll started",
+	EventPoll:           "poll",
+	EventJobDone:        "job done",
+}
+
+func (k EventKind) String() string {
+	if name, ok := eventKindNames[k]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Event is an event of a query.
+type Event struct {
+	Kind EventKind
+	// JobID is the ID of the job of the query.
+	JobID string
+	// State is the state of the job, only in EventPoll events.
+	State string
+	// Elapsed is the time elapsed since the query started to be submitted.
+	Elapsed time.Duration
+	// BytesProcessed is the total number of bytes processed by the query,
+	// only in EventJobDone events.
+	BytesProcessed int64
+	// Err is the error of the job if it failed, only in EventJobDone events.
+	Err error
+}
+
+// log sends the given event to the logger of the config, if any.
+func (c Config) log(ctx context.Context, since time.Time, event Event) {
+	if c.Logger == nil {
+		return
+	}
+
+	event.Elapsed = time.Since(since)
+	c.Logger.LogEvent(ctx, event)
+}
@@ -0,0 +1,60 @@
+package bigq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestServiceLogger(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.polls = 2
+
+	var events []Event
+	service := newFakeService(backend, Config{
+		Logger: LoggerFunc(func(ctx context.Context, event Event) {
+			events = append(events, event)
+		}),
+	})
+
+	_, err := service.Query(testQuery)
+	assert.Nil(err)
+
+	var kinds []EventKind
+	for _, e := range events {
+		kinds = append(kinds, e.Kind)
+		assert.Equal("job", e.JobID)
+	}
+	assert.Equal([]EventKind{
+		EventQuerySubmitted,
+		EventPollStarted,
+		EventPoll,
+		EventPoll,
+		EventJobDone,
+	}, kinds)
+	assert.Equal("RUNNING", events[2].State)
+	assert.Equal("DONE", events[3].State)
+	assert.Nil(events[4].Err)
+	assert.True(events[4].Elapsed >= events[2].Elapsed)
+}
+
+func TestServiceLoggerJobError(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.polls = 1
+	backend.jobError = &bigquery.ErrorProto{Message: "Syntax error"}
+
+	var done Event
+	service := newFakeService(backend, Config{
+		Logger: LoggerFunc(func(ctx context.Context, event Event) {
+			done = event
+		}),
+	})
+
+	_, err := service.Query(testQuery)
+	assert.Equal(EventJobDone, done.Kind)
+	assert.Equal(err, done.Err)
+}
@@ -0,0 +1,74 @@
+package bigq
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// MetricsCollector collects the metrics of the queries run by a service, so
+// they can be exported to any metrics system.
+type MetricsCollector interface {
+	// IncQuery is called every time a query is run.
+	IncQuery()
+	// ObserveQueryDuration is called with the time it took to run a query
+	// that succeeded, from its submission until its first page of results
+	// was retrieved.
+	ObserveQueryDuration(d time.Duration)
+	// ObserveBytesBilled is called with the number of bytes billed for a
+	// query once its job is done.
+	ObserveBytesBilled(bytes int64)
+	// IncError is called every time a query fails, with the reason of the
+	// error, such as "invalidQuery" or "rateLimitExceeded".
+	IncError(reason string)
+}
+
+// NopMetricsCollector is a MetricsCollector that discards all the metrics.
+// It is the collector used by default.
+type NopMetricsCollector struct{}
+
+// IncQuery does nothing.
+func (NopMetricsCollector) IncQuery() {}
+
+// ObserveQueryDuration does nothing.
+func (NopMetricsCollector) ObserveQueryDuration(time.Duration) {}
+
+// ObserveBytesBilled does nothing.
+func (NopMetricsCollector) ObserveBytesBilled(int64) {}
+
+// IncError does nothing.
+func (NopMetricsCollector) IncError(string) {}
+
+func (c Config) metrics() MetricsCollector {
+	if c.Metrics == nil {
+		return NopMetricsCollector{}
+	}
+	return c.Metrics
+}
+
+// errorReason returns the reason of the given error to be used in the
+// metrics. Errors of jobs and of the BigQuery API have their own reason,
+// the rest are "canceled", "timeout" or "unknown".
+func errorReason(err error) string {
+	switch err := err.(type) {
+	case *BytesBilledLimitError:
+		return bytesBilledLimitReason
+	case *JobError:
+		if err.Reason != "" {
+			return err.Reason
+		}
+	case *googleapi.Error:
+		if len(err.Errors) > 0 && err.Errors[0].Reason != "" {
+			return err.Errors[0].Reason
+		}
+	}
+
+	switch err {
+	case context.Canceled:
+		return "canceled"
+	case context.DeadlineExceeded, ErrQueryTimeout:
+		return "timeout"
+	}
+	return "unknown"
+}
@@ -0,0 +1,67 @@
+package bigq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+type fakeMetrics struct {
+	queries   int
+	durations []time.Duration
+	billed    []int64
+	errors    []string
+}
+
+func (m *fakeMetrics) IncQuery()                            { m.queries++ }
+func (m *fakeMetrics) ObserveQueryDuration(d time.Duration) { m.durations = append(m.durations, d) }
+func (m *fakeMetrics) ObserveBytesBilled(bytes int64)       { m.billed = append(m.billed, bytes) }
+func (m *fakeMetrics) IncError(reason string)               { m.errors = append(m.errors, reason) }
+
+func TestServiceMetrics(t *testing.T) {
+	assert := assert.New(t)
+	metrics := new(fakeMetrics)
+	backend := newFakeBackend(5)
+	backend.bytesBilled = 1024
+	service := newFakeService(backend, Config{Metrics: metrics})
+
+	_, err := service.Query(testQuery)
+	assert.Nil(err)
+
+	backend.polls = 2
+	_, err = service.Query(testQuery)
+	assert.Nil(err)
+
+	backend.polls = 1
+	backend.jobError = &bigquery.ErrorProto{Reason: "invalidQuery"}
+	_, err = service.Query(testQuery)
+	assert.NotNil(err)
+
+	assert.Equal(3, metrics.queries)
+	assert.Equal(2, len(metrics.durations))
+	assert.Equal([]int64{1024, 1024, 1024}, metrics.billed)
+	assert.Equal([]string{"invalidQuery"}, metrics.errors)
+}
+
+func TestErrorReason(t *testing.T) {
+	cases := []struct {
+		err    error
+		reason string
+	}{
+		{&JobError{Reason: "invalidQuery"}, "invalidQuery"},
+		{apiError(403, "rateLimitExceeded"), "rateLimitExceeded"},
+		{context.Canceled, "canceled"},
+		{context.DeadlineExceeded, "timeout"},
+		{ErrQueryTimeout, "timeout"},
+		{errors.New("foo"), "unknown"},
+	}
+
+	assert := assert.New(t)
+	for _, c := range cases {
+		assert.Equal(c.reason, errorReason(c.err), c.err.Error())
+	}
+}
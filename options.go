@@ -0,0 +1,98 @@
+package bigq
+
+import "fmt"
+
+// QueryOption is an option of a query.
+type QueryOption func(*queryOptions)
+
+type queryOptions struct {
+	start      uint64
+	maxResults uint64
+	jobID      string
+	// pageToken is the BigQuery token of the page of results at start.
+	pageToken string
+	maxRows   uint64
+	lenient   bool
+}
+
+// WithOffset sets the offset in the resultset where the query starts, that
+// is, the index of the first row of the first page. By default, it is 0.
+func WithOffset(n uint64) QueryOption {
+	return func(o *queryOptions) {
+		o.start = n
+	}
+}
+
+// WithPageSize sets the max number of results per page of the query. If it
+// is 0, the BigQuery default is used. By default, it is the
+// DefaultMaxResults of the config.
+func WithPageSize(n uint64) QueryOption {
+	return func(o *queryOptions) {
+		o.maxResults = n
+	}
+}
+
+// WithJobID sets the ID of the job of the query, which must be unique in the
+// project, e.g. to trace the job with the ID of the request that runs it. The
+// JobIDPrefix of the config is not added to the ID. As the ID of the job can
+// only be given when the query is inserted as a job, the first page of
+// results is fetched once the job is done. By default, the ID is random.
+func WithJobID(id string) QueryOption {
+	return func(o *queryOptions) {
+		o.jobID = id
+	}
+}
+
+// WithMaxRows sets the max number of rows retrieved from the resultset of the
+// query, after its start, as a safeguard of the memory used. Once they are
+// retrieved, no more pages are fetched and ErrRowsTruncated is returned if
+// there are more rows. It is not a LIMIT of the query, which still processes
+// all its rows. If it is 0, all the rows can be retrieved. By default, it is
+// the MaxRows of the config.
+func WithMaxRows(n uint64) QueryOption {
+	return func(o *queryOptions) {
+		o.maxRows = n
+	}
+}
+
+// WithLenientConversion makes the values of the resultset of the query that
+// can't be converted be set to their zero value, or nil in the rows converted
+// into maps, instead of failing the conversion, so the rest of the rows can
+// still be processed. The errors of these values are recorded and can be
+// retrieved with the Errors method of the query. It applies to Rows, ForEach,
+// Stream and the Scan and ScanStruct methods of Iter.
+func WithLenientConversion() QueryOption {
+	return func(o *queryOptions) {
+		o.lenient = true
+	}
+}
+
+// queryOptions returns the options of a query with the given options applied
+// to the defaults of the config.
+func (s *Service) queryOptions(opts ...QueryOption) queryOptions {
+	o := queryOptions{
+		maxResults: s.config.DefaultMaxResults,
+		maxRows:    s.config.MaxRows,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// queryArgs returns the options of a query with the given arguments, that
+// is, the start and the max results, in that order.
+func (s *Service) queryArgs(args ...uint64) (queryOptions, error) {
+	var opts []QueryOption
+	switch len(args) {
+	case 0:
+	case 2:
+		opts = append(opts, WithPageSize(args[1]))
+		fallthrough
+	case 1:
+		opts = append(opts, WithOffset(args[0]))
+	default:
+		return queryOptions{}, fmt.Errorf("too many arguments given to query: %d", len(args))
+	}
+	return s.queryOptions(opts...), nil
+}
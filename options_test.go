@@ -0,0 +1,73 @@
+package bigq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceQueryArgs(t *testing.T) {
+	cases := []struct {
+		args []uint64
+		opts queryOptions
+	}{
+		{nil, queryOptions{maxResults: 10}},
+		{[]uint64{5}, queryOptions{start: 5, maxResults: 10}},
+		{[]uint64{5, 2}, queryOptions{start: 5, maxResults: 2}},
+		{[]uint64{5, 0}, queryOptions{start: 5}},
+	}
+
+	assert := assert.New(t)
+	service := &Service{config: Config{DefaultMaxResults: 10}}
+	for _, c := range cases {
+		opts, err := service.queryArgs(c.args...)
+		assert.Nil(err)
+		assert.Equal(c.opts, opts, "%v", c.args)
+	}
+
+	_, err := service.queryArgs(1, 2, 3)
+	assert.NotNil(err)
+}
+
+func TestServiceQueryOpts(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{})
+
+	q, err := service.QueryOpts(testQuery, WithOffset(3), WithPageSize(1))
+	assert.Nil(err)
+	assert.Equal(int64(1), backend.requests[0].MaxResults)
+
+	rows, err := q.NextPage()
+	assert.Nil(err)
+	assert.Equal([][]interface{}{{"3"}}, rows)
+}
+
+func TestServiceQueryOffsetOutOfRange(t *testing.T) {
+	cases := []struct {
+		polls int
+		start uint64
+		err   error
+	}{
+		{0, 0, nil},
+		{0, 4, nil},
+		{0, 5, ErrOffsetOutOfRange},
+		{0, 6, ErrOffsetOutOfRange},
+		{2, 5, ErrOffsetOutOfRange},
+	}
+
+	assert := assert.New(t)
+	for _, c := range cases {
+		backend := newFakeBackend(5)
+		backend.polls = c.polls
+		service := newFakeService(backend, Config{})
+
+		_, err := service.QueryOpts(testQuery, WithOffset(c.start))
+		assert.Equal(c.err, err, "start %d", c.start)
+	}
+
+	service := newFakeService(newFakeBackend(0), Config{})
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+	assert.Equal(uint64(0), q.TotalRows())
+}
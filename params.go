@@ -0,0 +1,234 @@
+package bigq
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/bigquery/v2"
+)
+
+const (
+	namedParameterMode      = "NAMED"
+	positionalParameterMode = "POSITIONAL"
+
+	timestampParamFormat = "2006-01-02 15:04:05.999999-07:00"
+)
+
+// namedParams converts the given map of parameters into BigQuery query
+// parameters. The parameters are sorted by name so the request is always
+// the same for the same map.
+func namedParams(params map[string]interface{}) ([]*bigquery.QueryParameter, error) {
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var result []*bigquery.QueryParameter
+	for _, name := range names {
+		typ, val, err := paramValue(params[name])
+		if err != nil {
+			return nil, fmt.Errorf("invalid query parameter %q: %s", name, err)
+		}
+
+		result = append(result, &bigquery.QueryParameter{
+			Name:           name,
+			ParameterType:  typ,
+			ParameterValue: val,
+		})
+	}
+	return result, nil
+}
+
+// positionalParams converts the given values into unnamed BigQuery query
+// parameters in the same order they were given.
+func positionalParams(params []interface{}) ([]*bigquery.QueryParameter, error) {
+	var result []*bigquery.QueryParameter
+	for i, p := range params {
+		typ, val, err := paramValue(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid query parameter at position %d: %s", i, err)
+		}
+
+		result = append(result, &bigquery.QueryParameter{
+			ParameterType:  typ,
+			ParameterValue: val,
+		})
+	}
+	return result, nil
+}
+
+// paramValue returns the BigQuery type and value of the given Go value.
+// Supported types are string, int, int64, float64, bool, time.Time and []byte,
+// slices of them, which are ARRAY parameters, and structs whose fields are of
+// the supported types, which are STRUCT parameters. The fields of the structs
+// are named with their "bigquery" tag or, if they don't have one, with their
+// Go field name as it is, without applying the NameMapper of the service.
+// Fields tagged with "-" are ignored.
+func paramValue(v interface{}) (*bigquery.QueryParameterType, *bigquery.QueryParameterValue, error) {
+	switch v := v.(type) {
+	case string:
+		return scalarParam("STRING", v)
+	case int:
+		return scalarParam("INT64", strconv.Itoa(v))
+	case int64:
+		return scalarParam("INT64", strconv.FormatInt(v, 10))
+	case float64:
+		return scalarParam("FLOAT64", strconv.FormatFloat(v, 'g', -1, 64))
+	case bool:
+		return scalarParam("BOOL", strconv.FormatBool(v))
+	case time.Time:
+		return scalarParam("TIMESTAMP", v.UTC().Format(timestampParamFormat))
+	case []byte:
+		return scalarParam("BYTES", base64.StdEncoding.EncodeToString(v))
+	}
+
+	switch rv := reflect.ValueOf(v); rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		return arrayParam(rv)
+	case reflect.Struct:
+		return structParam(rv)
+	default:
+		return nil, nil, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// arrayParam returns the BigQuery type and value of the given slice or
+// array. The type of the elements is the one of the Go type of the elements,
+// so it is known even if there are no elements.
+func arrayParam(v reflect.Value) (*bigquery.QueryParameterType, *bigquery.QueryParameterValue, error) {
+	elem := v.Type().Elem()
+	if k := elem.Kind(); k == reflect.Slice || k == reflect.Array {
+		if elem.Elem().Kind() != reflect.Uint8 {
+			return nil, nil, fmt.Errorf("unsupported type %s, arrays of arrays are not supported", v.Type())
+		}
+	}
+
+	elemType, _, err := paramValue(reflect.Zero(elem).Interface())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	values := make([]*bigquery.QueryParameterValue, v.Len())
+	for i := range values {
+		if _, values[i], err = paramValue(v.Index(i).Interface()); err != nil {
+			return nil, nil, fmt.Errorf("element %d: %s", i, err)
+		}
+	}
+
+	return &bigquery.QueryParameterType{Type: "ARRAY", ArrayType: elemType},
+		// empty arrays need to be sent, otherwise they would be a NULL
+		&bigquery.QueryParameterValue{ArrayValues: values, ForceSendFields: []string{"ArrayValues"}},
+		nil
+}
+
+// structParam returns the BigQuery type and value of the given struct, with
+// a field for each one of its exported fields, in the same order.
+func structParam(v reflect.Value) (*bigquery.QueryParameterType, *bigquery.QueryParameterValue, error) {
+	typ := &bigquery.QueryParameterType{Type: "STRUCT"}
+	val := &bigquery.QueryParameterValue{StructValues: make(map[string]bigquery.QueryParameterValue)}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := f.Tag.Get("bigquery")
+		if f.PkgPath != "" || name == "-" {
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+
+		fieldType, fieldValue, err := paramValue(v.Field(i).Interface())
+		if err != nil {
+			return nil, nil, fmt.Errorf("field %s: %s", f.Name, err)
+		}
+
+		typ.StructTypes = append(typ.StructTypes, &bigquery.QueryParameterTypeStructTypes{
+			Name: name,
+			Type: fieldType,
+		})
+		val.StructValues[name] = *fieldValue
+	}
+
+	if len(typ.StructTypes) == 0 {
+		return nil, nil, fmt.Errorf("unsupported type %s, structs must have exported fields", t)
+	}
+	return typ, val, nil
+}
+
+func scalarParam(typ, value string) (*bigquery.QueryParameterType, *bigquery.QueryParameterValue, error) {
+	return &bigquery.QueryParameterType{Type: typ},
+		// the value needs to be sent even if it's empty, otherwise it
+		// would be a NULL
+		&bigquery.QueryParameterValue{Value: value, ForceSendFields: []string{"Value"}},
+		nil
+}
+
+var errMixedParams = errors.New("named and positional query parameters can't be mixed in the same query")
+
+// paramStyles reports whether the given SQL sentence uses named (@name) and
+// positional (?) parameters. String literals, quoted identifiers, comments
+// and system variables (@@name) are ignored.
+func paramStyles(sql string) (named, positional bool) {
+	for i := 0; i < len(sql); i++ {
+		switch c := sql[i]; c {
+		case '\'', '"', '`':
+			i = skipQuoted(sql, i, c)
+		case '#':
+			i = skipLine(sql, i)
+		case '-':
+			if i+1 < len(sql) && sql[i+1] == '-' {
+				i = skipLine(sql, i)
+			}
+		case '/':
+			if i+1 < len(sql) && sql[i+1] == '*' {
+				if end := strings.Index(sql[i+2:], "*/"); end >= 0 {
+					i += end + 3
+				} else {
+					i = len(sql)
+				}
+			}
+		case '?':
+			positional = true
+		case '@':
+			if i+1 < len(sql) && sql[i+1] == '@' {
+				// skip the whole system variable prefix
+				i++
+			} else if i+1 < len(sql) && isIdentStart(sql[i+1]) {
+				named = true
+			}
+		}
+	}
+	return named, positional
+}
+
+func skipQuoted(sql string, start int, quote byte) int {
+	for i := start + 1; i < len(sql); i++ {
+		switch sql[i] {
+		case '\\':
+			i++
+		case quote:
+			return i
+		}
+	}
+	return len(sql)
+}
+
+func skipLine(sql string, start int) int {
+	if end := strings.IndexByte(sql[start:], '\n'); end >= 0 {
+		return start + end
+	}
+	return len(sql)
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,164 @@
+package bigq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParamValue(t *testing.T) {
+	cases := []struct {
+		value interface{}
+		typ   string
+		str   string
+	}{
+		{"foo", "STRING", "foo"},
+		{"", "STRING", ""},
+		{42, "INT64", "42"},
+		{int64(-7), "INT64", "-7"},
+		{3.5, "FLOAT64", "3.5"},
+		{true, "BOOL", "true"},
+		{time.Date(2016, 3, 4, 5, 6, 7, 8000, time.UTC), "TIMESTAMP", "2016-03-04 05:06:07.000008+00:00"},
+		{[]byte("hi"), "BYTES", "aGk="},
+	}
+
+	assert := assert.New(t)
+	for _, c := range cases {
+		typ, val, err := paramValue(c.value)
+		assert.Nil(err)
+		assert.Equal(c.typ, typ.Type)
+		assert.Equal(c.str, val.Value)
+	}
+}
+
+func TestParamValueUnsupported(t *testing.T) {
+	assert := assert.New(t)
+	_, _, err := paramValue(struct{}{})
+	assert.NotNil(err)
+}
+
+func TestParamValueArray(t *testing.T) {
+	assert := assert.New(t)
+	typ, val, err := paramValue([]int64{1, 2})
+	assert.Nil(err)
+	assert.Equal("ARRAY", typ.Type)
+	assert.Equal("INT64", typ.ArrayType.Type)
+	assert.Equal(2, len(val.ArrayValues))
+	assert.Equal("1", val.ArrayValues[0].Value)
+	assert.Equal("2", val.ArrayValues[1].Value)
+
+	typ, val, err = paramValue([]string{})
+	assert.Nil(err)
+	assert.Equal("STRING", typ.ArrayType.Type)
+	assert.Equal(0, len(val.ArrayValues))
+	assert.Equal([]string{"ArrayValues"}, val.ForceSendFields)
+
+	typ, _, err = paramValue([][]byte{[]byte("hi")})
+	assert.Nil(err)
+	assert.Equal("BYTES", typ.ArrayType.Type)
+
+	_, _, err = paramValue([][]string{{"foo"}})
+	assert.NotNil(err)
+}
+
+func TestParamValueStruct(t *testing.T) {
+	assert := assert.New(t)
+	type user struct {
+		ID     int64 `bigquery:"user_id"`
+		Name   string
+		Tags   []string
+		Secret string `bigquery:"-"`
+		age    int
+	}
+
+	typ, val, err := paramValue(user{ID: 7, Name: "zeal", Tags: []string{"a"}, age: 3})
+	assert.Nil(err)
+	assert.Equal("STRUCT", typ.Type)
+	assert.Equal(3, len(typ.StructTypes))
+	assert.Equal("user_id", typ.StructTypes[0].Name)
+	assert.Equal("INT64", typ.StructTypes[0].Type.Type)
+	assert.Equal("Name", typ.StructTypes[1].Name)
+	assert.Equal("ARRAY", typ.StructTypes[2].Type.Type)
+	assert.Equal("7", val.StructValues["user_id"].Value)
+	assert.Equal("zeal", val.StructValues["Name"].Value)
+	assert.Equal("a", val.StructValues["Tags"].ArrayValues[0].Value)
+
+	typ, _, err = paramValue([]user{})
+	assert.Nil(err)
+	assert.Equal("STRUCT", typ.ArrayType.Type)
+}
+
+func TestServiceQueryWithArrayParam(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{})
+
+	_, err := service.QueryWithParams(
+		"SELECT id FROM users WHERE id IN UNNEST(@ids)",
+		map[string]interface{}{"ids": []int64{1, 2, 3}},
+	)
+	assert.Nil(err)
+
+	params := backend.requests[0].QueryParameters
+	assert.Equal(1, len(params))
+	assert.Equal("ids", params[0].Name)
+	assert.Equal("ARRAY", params[0].ParameterType.Type)
+	assert.Equal("INT64", params[0].ParameterType.ArrayType.Type)
+	assert.Equal(3, len(params[0].ParameterValue.ArrayValues))
+	assert.Equal("3", params[0].ParameterValue.ArrayValues[2].Value)
+}
+
+func TestNamedParams(t *testing.T) {
+	assert := assert.New(t)
+	params, err := namedParams(map[string]interface{}{
+		"word":  "zeal",
+		"count": 2,
+	})
+	assert.Nil(err)
+	assert.Equal(2, len(params))
+	assert.Equal("count", params[0].Name)
+	assert.Equal("INT64", params[0].ParameterType.Type)
+	assert.Equal("word", params[1].Name)
+	assert.Equal("zeal", params[1].ParameterValue.Value)
+
+	_, err = namedParams(map[string]interface{}{"foo": []complex128{1}})
+	assert.NotNil(err)
+}
+
+func TestPositionalParams(t *testing.T) {
+	assert := assert.New(t)
+	params, err := positionalParams([]interface{}{"zeal", int64(2)})
+	assert.Nil(err)
+	assert.Equal(2, len(params))
+	assert.Equal("", params[0].Name)
+	assert.Equal("STRING", params[0].ParameterType.Type)
+	assert.Equal("2", params[1].ParameterValue.Value)
+
+	_, err = positionalParams([]interface{}{"zeal", nil})
+	assert.NotNil(err)
+}
+
+func TestParamStyles(t *testing.T) {
+	cases := []struct {
+		sql        string
+		named      bool
+		positional bool
+	}{
+		{"SELECT 1", false, false},
+		{"SELECT * FROM t WHERE id = @id", true, false},
+		{"SELECT * FROM t WHERE id = ?", false, true},
+		{"SELECT * FROM t WHERE id = ? AND name = @name", true, true},
+		{"SELECT '@foo', \"?\", `a?b` FROM t", false, false},
+		{"SELECT 'it\\'s ?' FROM t WHERE id = @id", true, false},
+		{"SELECT @@dataset_id", false, false},
+		{"SELECT 1 -- is it?\nFROM t # @foo\nWHERE /* ? */ id = @id", true, false},
+	}
+
+	assert := assert.New(t)
+	for _, c := range cases {
+		named, positional := paramStyles(c.sql)
+		assert.Equal(c.named, named, c.sql)
+		assert.Equal(c.positional, positional, c.sql)
+	}
+}
@@ -0,0 +1,61 @@
+package bigq
+
+import (
+	"errors"
+	"sort"
+
+	"google.golang.org/api/bigquery/v2"
+)
+
+var errEmptyPropertyKey = errors.New("invalid connection property: the key can't be empty")
+
+// WithConnectionProperties returns a copy of the service, using the same
+// connection, that runs its queries with the given connection properties, in
+// addition to the connection properties of its config. Properties with the
+// same key replace the ones of the config. The properties are given to
+// BigQuery as they are, e.g. "time_zone" sets the default time zone of the
+// date and time functions of the queries.
+func (s *Service) WithConnectionProperties(properties map[string]string) *Service {
+	svc := *s
+	merged := make(map[string]string, len(s.config.ConnectionProperties)+len(properties))
+	for k, v := range s.config.ConnectionProperties {
+		merged[k] = v
+	}
+	for k, v := range properties {
+		merged[k] = v
+	}
+	svc.config.ConnectionProperties = merged
+	return &svc
+}
+
+// connectionProperties returns the given connection properties in the format
+// of the requests, sorted by their key so the requests are always the same
+// for the same properties.
+func connectionProperties(properties map[string]string) []*bigquery.ConnectionProperty {
+	if len(properties) == 0 {
+		return nil
+	}
+
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	result := make([]*bigquery.ConnectionProperty, len(keys))
+	for i, k := range keys {
+		result[i] = &bigquery.ConnectionProperty{Key: k, Value: properties[k]}
+	}
+	return result
+}
+
+// validateConnectionProperties returns an error if any of the given
+// connection properties has no key.
+func validateConnectionProperties(properties []*bigquery.ConnectionProperty) error {
+	for _, p := range properties {
+		if p.Key == "" {
+			return errEmptyPropertyKey
+		}
+	}
+	return nil
+}
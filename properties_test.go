@@ -0,0 +1,40 @@
+package bigq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestServiceWithConnectionProperties(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	service := newFakeService(backend, Config{
+		ConnectionProperties: map[string]string{"time_zone": "UTC", "query_label": "team:data"},
+	})
+
+	_, err := service.Query(testQuery)
+	assert.Nil(err)
+	assert.Equal([]*bigquery.ConnectionProperty{
+		{Key: "query_label", Value: "team:data"},
+		{Key: "time_zone", Value: "UTC"},
+	}, backend.requests[0].ConnectionProperties)
+
+	derived := service.WithConnectionProperties(map[string]string{"time_zone": "Europe/Madrid"})
+	_, err = derived.QueryOpts(testQuery, WithJobID("madrid"))
+	assert.Nil(err)
+	assert.Equal([]*bigquery.ConnectionProperty{
+		{Key: "query_label", Value: "team:data"},
+		{Key: "time_zone", Value: "Europe/Madrid"},
+	}, backend.inserted[0].Configuration.Query.ConnectionProperties)
+	assert.Equal("UTC", service.config.ConnectionProperties["time_zone"])
+
+	_, err = newFakeService(backend, Config{}).Query(testQuery)
+	assert.Nil(err)
+	assert.Nil(backend.requests[1].ConnectionProperties)
+
+	_, err = service.WithConnectionProperties(map[string]string{"": "foo"}).Query(testQuery)
+	assert.Equal(errEmptyPropertyKey, err)
+	assert.Equal(2, backend.calls["Query"])
+}
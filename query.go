@@ -1,11 +1,76 @@
 package bigq
 
 import (
+	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
 
 	"google.golang.org/api/bigquery/v2"
 )
 
+// QueryStatistics are the statistics of the job of a query.
+type QueryStatistics struct {
+	// StartTime is the time the job started running.
+	StartTime time.Time
+	// EndTime is the time the job finished.
+	EndTime time.Time
+	// BytesProcessed is the number of bytes processed by the query.
+	BytesProcessed int64
+	// BytesBilled is the number of bytes billed for the query.
+	BytesBilled int64
+	// SlotMs is the number of slot-milliseconds consumed by the query.
+	SlotMs int64
+	// CacheHit reports whether the results were served from the query cache.
+	CacheHit bool
+	// NumDmlAffectedRows is the number of rows inserted, updated or deleted
+	// by a DML statement.
+	NumDmlAffectedRows int64
+}
+
+// QueryStage is a stage of the execution plan of a query. The ratios are
+// relative to the longest time spent by any worker in any stage.
+type QueryStage struct {
+	// ID is the unique ID of the stage within the plan.
+	ID int64
+	// Name is the human-readable name of the stage.
+	Name string
+	// Status is the current status of the stage, such as "COMPLETE".
+	Status string
+	// RecordsRead is the number of records read into the stage.
+	RecordsRead int64
+	// RecordsWritten is the number of records written by the stage.
+	RecordsWritten int64
+	// WaitRatioAvg is the relative time the average worker spent waiting to
+	// be scheduled.
+	WaitRatioAvg float64
+	// WaitRatioMax is the relative time the slowest worker spent waiting to
+	// be scheduled.
+	WaitRatioMax float64
+	// ReadRatioAvg is the relative time the average worker spent reading
+	// input.
+	ReadRatioAvg float64
+	// ReadRatioMax is the relative time the slowest worker spent reading
+	// input.
+	ReadRatioMax float64
+	// ComputeRatioAvg is the relative time the average worker spent on CPU
+	// bound tasks.
+	ComputeRatioAvg float64
+	// ComputeRatioMax is the relative time the slowest worker spent on CPU
+	// bound tasks.
+	ComputeRatioMax float64
+	// WriteRatioAvg is the relative time the average worker spent writing
+	// output.
+	WriteRatioAvg float64
+	// WriteRatioMax is the relative time the slowest worker spent writing
+	// output.
+	WriteRatioMax float64
+}
+
 // Query contains all the context of a query execution and has methods to
 // retrieve the rows in pages. The Query instance can be seen as a cursor,
 // it can't go back and it can't re-read the same page again. It is not thread
@@ -17,50 +82,312 @@ type Query interface {
 	// it will yield and will not return the max number of results instead.
 	NextPage() ([][]interface{}, error)
 
+	// Rows returns the next page of rows in the query resultset, the same way
+	// NextPage does, but with every row as a map of column names to their
+	// values converted to Go types according to the schema. RECORD columns
+	// are converted into nested maps and REPEATED columns into slices.
+	Rows() ([]map[string]interface{}, error)
+
+	// AppendRows is like Rows, but the rows of the next page are appended to
+	// dst, which is returned grown like with append, so a slice can be reused
+	// to retrieve the pages, e.g. by giving it back with dst[:0]. As with
+	// append, the returned slice may share the array of dst, so dst must not
+	// be used afterwards, and the rows in the array of dst past its length are
+	// overwritten. If there is an error, dst is returned as it was given.
+	AppendRows(dst []map[string]interface{}) ([]map[string]interface{}, error)
+
+	// ForEach calls the given function with every row of the query resultset
+	// that has not been retrieved yet, converted the same way as in Rows,
+	// fetching the pages as they are needed, so the rows are never all in
+	// memory. It stops at the first error returned by the function, which is
+	// returned, or if the context of the query is done. Using this method sets
+	// the query in "all" mode, that is, the NextPage method can't be used after
+	// using ForEach.
+	ForEach(fn func(row map[string]interface{}) error) error
+
 	// Iter returns an iterator to retrieve the query results.
 	// Using this method sets the query in "iter" mode, that is,
 	// the NextPage method can't be used after using Iter, but it
 	// can be used before retrieving the iterator.
 	Iter() Iter
+
+	// All returns an iterator to retrieve all the rows of the query
+	// resultset, one by one, fetching the pages as they are needed.
+	// Using this method sets the query in "all" mode, that is,
+	// the NextPage method can't be used after using All, but it
+	// can be used before retrieving the iterator.
+	All() RowIter
+
+	// Stream retrieves all the rows of the query resultset in the background,
+	// fetching the pages as they are needed, and sends them one by one on the
+	// returned channel, which is closed once all the rows are sent. If there is
+	// an error, it is sent on the error channel and no more rows are sent. The
+	// stream stops when the given context is cancelled, in which case the error
+	// of the context is sent. The error channel is always closed after the rows
+	// channel. Using this method sets the query in "stream" mode, that is, the
+	// NextPage method can't be used after using Stream.
+	Stream(ctx context.Context) (<-chan Row, <-chan error)
+
+	// WriteCSV writes the rows of the query resultset that have not been
+	// retrieved yet to the given writer as CSV, fetching the pages as they are
+	// needed, preceded by a header row with the names of the columns. The values
+	// are converted the same way they are converted by Rows and written in
+	// their canonical format, with TIMESTAMP values in RFC 3339 format. RECORD
+	// and REPEATED values are written as JSON. NULL values are written as empty
+	// cells. Using this method sets the query in "all" mode, that is, the
+	// NextPage method can't be used after using WriteCSV.
+	WriteCSV(w io.Writer) error
+
+	// WriteJSON writes the rows of the query resultset that have not been
+	// retrieved yet to the given writer as newline-delimited JSON, fetching the
+	// pages as they are needed, with every row as a JSON object of column names
+	// to their values. The values are converted the same way as in WriteCSV,
+	// but keeping their types, that is, numbers and booleans are written as
+	// JSON numbers and booleans, and RECORD and REPEATED values as JSON
+	// objects and arrays. Using this method sets the query in "all" mode, that
+	// is, the NextPage method can't be used after using WriteJSON.
+	WriteJSON(w io.Writer) error
+
+	// Warnings returns the messages of the errors the query job completed
+	// with, if any. These errors are not fatal, the job completed anyway,
+	// but the results may be partial, e.g. if some of the data could not be
+	// read. See the Strict field of the config to make them fail the query.
+	Warnings() []string
+
+	// Errors returns the errors of the values of the rows retrieved so far
+	// that could not be converted, in order, if the query was run with
+	// WithLenientConversion. These values were set to their zero value. When
+	// the rows are streamed, it must be called once the stream is done.
+	Errors() []*ConversionError
+
+	// Schema returns the fields of the schema of the query resultset. Each
+	// field contains, among others, its name, type and mode.
+	Schema() []*bigquery.TableFieldSchema
+
+	// TotalRows returns the total number of rows in the query resultset,
+	// across all pages, not just the rows in the pages retrieved. As a query
+	// is always created once its job is complete, the total is always known.
+	TotalRows() uint64
+
+	// PageCount returns the number of pages of the query resultset, with
+	// pages of the page size of the query. If there is no page size, all
+	// the rows are considered to be in a single page, although BigQuery may
+	// still split them to keep the pages under its size limit. There is
+	// always at least one page, even if the resultset is empty.
+	PageCount() int
+
+	// CurrentPage returns the index of the page of the last rows retrieved,
+	// starting at 0, computed from their offset in the resultset and the
+	// page size of the query. Before any rows are retrieved, it is the page
+	// at the start of the query.
+	CurrentPage() int
+
+	// CacheHit reports whether the query results were served from the
+	// query cache.
+	CacheHit() bool
+
+	// JobID returns the ID of the BigQuery job that ran the query.
+	JobID() string
+
+	// Location returns the geographic location where the job of the query
+	// was run.
+	Location() string
+
+	// BytesProcessed returns the total number of bytes processed by the query.
+	// The statistics of the query job are retrieved the first time they are
+	// needed and reused afterwards.
+	BytesProcessed() (int64, error)
+
+	// BytesBilled returns the total number of bytes billed for the query.
+	// The statistics of the query job are retrieved the first time they are
+	// needed and reused afterwards.
+	BytesBilled() (int64, error)
+
+	// TotalSlotMs returns the total number of slot-milliseconds consumed by
+	// the query, which shows how much compute the query used regardless of
+	// the bytes it processed. The statistics of the query job are retrieved
+	// the first time they are needed and reused afterwards.
+	TotalSlotMs() (int64, error)
+
+	// StartTime returns the time the query job started running, according to
+	// BigQuery. The query job is retrieved the first time it is needed and
+	// reused afterwards.
+	StartTime() (time.Time, error)
+
+	// EndTime returns the time the query job finished, according to
+	// BigQuery. The query job is retrieved the first time it is needed and
+	// reused afterwards.
+	EndTime() (time.Time, error)
+
+	// Duration returns the time it took BigQuery to run the query job, from
+	// its start to its end, which does not include the time spent waiting for
+	// the job to finish. The query job is retrieved the first time it is
+	// needed and reused afterwards.
+	Duration() (time.Duration, error)
+
+	// Stats returns all the statistics of the query job at once, e.g. for
+	// audit logging. The statistics of the query job are retrieved the first
+	// time they are needed and reused afterwards.
+	Stats() (*QueryStatistics, error)
+
+	// QueryPlan returns the stages of the execution plan of the query, the
+	// same ones shown in the execution details of the BigQuery console, e.g.
+	// to check the performance of the query. The query job is retrieved the
+	// first time it is needed and reused afterwards.
+	QueryPlan() ([]QueryStage, error)
+
+	// ResultTable returns the project, dataset and ID of the table the
+	// results of the query were written to, which is an anonymous table that
+	// expires after 24h, unless the query was given a destination table. It
+	// can be queried directly or deleted early. The query job is retrieved
+	// the first time it is needed and reused afterwards.
+	ResultTable() (projectID, datasetID, tableID string, err error)
+
+	// ResultFingerprint returns a key that identifies the data of the results
+	// of the query, made of the table of the results and the time it was
+	// last modified, e.g. to detect when the results cached by an application
+	// are stale. Running the same query again returns the same fingerprint
+	// if its results are served from the query cache, see CacheHit, and a
+	// different one if the data changed. The table is retrieved every time.
+	ResultFingerprint() (string, error)
+
+	// PageToken returns an opaque token of the next page of results to be
+	// retrieved, which can be given to QueryPage to resume the pagination of
+	// the results later, even by another process. It is empty if there are
+	// no more rows to retrieve.
+	PageToken() string
+
+	// HasMorePages reports whether there are rows of the resultset after the
+	// ones retrieved so far, that is, if the next page has any rows. The
+	// rows after the max rows of the query, if any, are also taken into
+	// account, although they are not retrieved and ErrRowsTruncated is
+	// returned instead.
+	HasMorePages() bool
+
+	// NextPageQuery returns a new query, for the same job, that starts at the
+	// page after the first page of this query, that is, its start plus its
+	// max results, so pages can be navigated without any arithmetic. The
+	// query must have been given its max results. ErrNoMorePages is returned
+	// if there are no rows after the first page of this query.
+	NextPageQuery() (Query, error)
+
+	// PrevPageQuery returns a new query, for the same job, that starts at the
+	// page before the first page of this query, that is, its start minus its
+	// max results, or 0 if there are less rows before. The query must have
+	// been given its max results. ErrNoPrevPage is returned if this query
+	// starts at the beginning of the resultset.
+	PrevPageQuery() (Query, error)
 }
 
 type query struct {
-	service     *bigquery.Service
+	ctx         context.Context
+	backend     backend
 	jobID       string
+	location    string
 	projectID   string
+	start       uint64
 	pageToken   string
 	sentRows    uint64
 	maxResults  uint64
+	schema      []*bigquery.TableFieldSchema
+	totalRows   uint64
+	cacheHit    bool
 	initialRows []*bigquery.TableRow
 	mode        queryResultMode
+	job         *bigquery.Job
+	warnings    []string
+	// retry is the policy used to retry the requests of the pages.
+	retry RetryPolicy
+	// nameMapper names the fields of the structs the rows are scanned into.
+	nameMapper NameMapper
+	// maxRows is the max number of rows retrieved after start, if any.
+	maxRows uint64
+	// lenient reports whether the values that can't be converted are
+	// recorded in conversionErrors instead of failing.
+	lenient          bool
+	conversionErrors []*ConversionError
+	// pageStart is the index of the first row of the last page fetched.
+	pageStart uint64
 }
 
+// newQuery creates a new query for the job of the given page of results,
+// which must be the page of rows starting at start, if it has rows. The rows
+// of the page are served as the first page of the query, and the next pages
+// are fetched as they are requested.
 func newQuery(
-	service *bigquery.Service,
-	resp *bigquery.QueryResponse,
+	ctx context.Context,
+	backend backend,
+	page *bigquery.GetQueryResultsResponse,
 	projectID string,
 	start uint64,
 	maxResults uint64,
-) Query {
-	var rows []*bigquery.TableRow
-	if resp.JobComplete && start == 0 {
-		rows = resp.Rows
+) *query {
+	var schema []*bigquery.TableFieldSchema
+	if page.Schema != nil {
+		schema = page.Schema.Fields
+	}
+
+	var warnings []string
+	for _, e := range page.Errors {
+		warnings = append(warnings, e.Message)
+	}
+
+	var pageToken string
+	if len(page.Rows) > 0 {
+		// the token is the one of the page after the rows of the page
+		pageToken = page.PageToken
 	}
 
 	return &query{
-		jobID:       resp.JobReference.JobId,
+		ctx:         ctx,
+		jobID:       page.JobReference.JobId,
+		location:    page.JobReference.Location,
 		projectID:   projectID,
-		service:     service,
+		start:       start,
+		pageToken:   pageToken,
+		backend:     backend,
 		sentRows:    start,
-		initialRows: rows,
+		pageStart:   start,
+		schema:      schema,
+		totalRows:   page.TotalRows,
+		cacheHit:    page.CacheHit,
+		initialRows: page.Rows,
 		maxResults:  maxResults,
 		mode:        pageMode,
+		warnings:    warnings,
+	}
+}
+
+// queryResultsPage returns the page of results contained in the response of
+// a query.
+func queryResultsPage(resp *bigquery.QueryResponse) *bigquery.GetQueryResultsResponse {
+	return &bigquery.GetQueryResultsResponse{
+		CacheHit:            resp.CacheHit,
+		Errors:              resp.Errors,
+		JobComplete:         resp.JobComplete,
+		JobReference:        resp.JobReference,
+		NumDmlAffectedRows:  resp.NumDmlAffectedRows,
+		PageToken:           resp.PageToken,
+		Rows:                resp.Rows,
+		Schema:              resp.Schema,
+		TotalBytesProcessed: resp.TotalBytesProcessed,
+		TotalRows:           resp.TotalRows,
 	}
 }
 
 var (
-	errAlreadyReading = errors.New("can't use NextPage after calling All")
-	errInvalidMode    = errors.New("invalid mode: can't use NextPage after using Iter")
+	errAlreadyReading   = errors.New("can't use NextPage after calling All")
+	errInvalidMode      = errors.New("invalid mode: can't use NextPage after using Iter")
+	errStreaming        = errors.New("can't use NextPage after calling Stream")
+	errNoPageSize       = errors.New("the page can't be changed without the max results of the query")
+	errInvalidPageToken = errors.New("invalid page token")
+
+	// ErrNoMorePages is returned when the query of the next page is requested
+	// and there are no more rows.
+	ErrNoMorePages = errors.New("there are no more pages")
+	// ErrNoPrevPage is returned when the query of the previous page is
+	// requested and the query starts at the beginning of the resultset.
+	ErrNoPrevPage = errors.New("there is no previous page")
 )
 
 type queryResultMode int
@@ -68,6 +395,8 @@ type queryResultMode int
 const (
 	pageMode queryResultMode = 1 << iota
 	iterMode
+	allMode
+	streamMode
 )
 
 // NextPage returns the next page of rows in the query resultset. It returns up
@@ -75,39 +404,142 @@ const (
 // BigQuery has a limit of 10MB, that is, when your page reaches the 10MB limit
 // it will yield and will not return the max number of results instead.
 func (q *query) NextPage() ([][]interface{}, error) {
-	if q.mode != pageMode {
+	switch q.mode {
+	case pageMode:
+		return q.nextPage()
+	case allMode:
+		return nil, errAlreadyReading
+	case streamMode:
+		return nil, errStreaming
+	default:
 		return nil, errInvalidMode
 	}
-	return q.nextPage()
+}
+
+// Rows returns the next page of rows in the query resultset, the same way
+// NextPage does, but with every row as a map of column names to their
+// values converted to Go types according to the schema. RECORD columns
+// are converted into nested maps and REPEATED columns into slices.
+func (q *query) Rows() ([]map[string]interface{}, error) {
+	return q.AppendRows(nil)
+}
+
+// AppendRows is like Rows, but the rows of the next page are appended to
+// dst, which is returned grown like with append, so a slice can be reused
+// to retrieve the pages, e.g. by giving it back with dst[:0]. As with
+// append, the returned slice may share the array of dst, so dst must not
+// be used afterwards, and the rows in the array of dst past its length are
+// overwritten. If there is an error, dst is returned as it was given.
+func (q *query) AppendRows(dst []map[string]interface{}) ([]map[string]interface{}, error) {
+	rows, err := q.NextPage()
+	if err != nil {
+		return dst, err
+	}
+
+	result := dst
+	for i, row := range rows {
+		m, err := rowMap(q.schema, row, q.cellErrors(i))
+		if err != nil {
+			return dst, err
+		}
+		result = append(result, m)
+	}
+	return result, nil
+}
+
+// ForEach calls the given function with every row of the query resultset
+// that has not been retrieved yet, converted the same way as in Rows,
+// fetching the pages as they are needed, so the rows are never all in
+// memory. It stops at the first error returned by the function, which is
+// returned, or if the context of the query is done. Using this method sets
+// the query in "all" mode, that is, the NextPage method can't be used after
+// using ForEach.
+func (q *query) ForEach(fn func(row map[string]interface{}) error) error {
+	rows := q.All().(*rowIter)
+	for {
+		row, ok := rows.Next()
+		if !ok {
+			return rows.Err()
+		}
+
+		m, err := rowMap(q.schema, row, q.cellErrors(rows.it.idx-1))
+		if err != nil {
+			return err
+		}
+
+		if err := q.ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := fn(m); err != nil {
+			return err
+		}
+	}
 }
 
 func (q *query) nextPage() ([][]interface{}, error) {
-	if q.sentRows == 0 && len(q.initialRows) > 0 {
-		q.sentRows += uint64(len(q.initialRows))
-		return transformRows(q.initialRows), nil
+	return q.fetchPage(q.ctx)
+}
+
+// fetchPage returns the next page of rows, using the given context to fetch
+// it if needed. The rows are truncated to the max rows of the query, if any,
+// and ErrRowsTruncated is returned once they were all retrieved if there are
+// more rows.
+func (q *query) fetchPage(ctx context.Context) ([][]interface{}, error) {
+	q.pageStart = q.sentRows
+	if q.maxRows == 0 {
+		return q.fetchRows(ctx)
 	}
 
-	if len(q.initialRows) > 0 {
-		// no need to hold the reference anymore
-		q.initialRows = nil
+	if q.sentRows-q.start >= q.maxRows {
+		if q.sentRows < q.totalRows {
+			return nil, ErrRowsTruncated
+		}
+		return nil, nil
+	}
+
+	rows, err := q.fetchRows(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	call := q.service.Jobs.GetQueryResults(q.projectID, q.jobID)
-	call.StartIndex(q.sentRows)
+	if extra := q.sentRows - q.start; extra > q.maxRows {
+		rows = rows[:uint64(len(rows))-(extra-q.maxRows)]
+		q.sentRows = q.start + q.maxRows
+		// the next page starts in the middle of the page of the token
+		q.pageToken = ""
+	}
+	return rows, nil
+}
 
-	if q.maxResults > 0 {
-		call.MaxResults(int64(q.maxResults))
+// fetchRows returns the next page of rows, using the given context to fetch
+// it if needed.
+func (q *query) fetchRows(ctx context.Context) ([][]interface{}, error) {
+	if q.initialRows != nil {
+		rows := q.initialRows
+		// no need to hold the reference anymore
+		q.initialRows = nil
+		q.sentRows += uint64(len(rows))
+		return transformRows(rows), nil
 	}
 
-	if q.pageToken != "" {
-		call.PageToken(q.pageToken)
+	if q.sentRows >= q.totalRows {
+		// there are no more rows to fetch
+		return nil, nil
 	}
 
-	results, err := call.Do()
+	// the state of the query is only updated once the page is fetched, so
+	// the same page is requested again if it fails
+	results, err := q.getQueryResults(ctx, q.sentRows, q.pageToken)
 	if err != nil {
 		return nil, err
 	}
 
+	if q.schema == nil && results.Schema != nil {
+		q.schema = results.Schema.Fields
+	}
+
+	q.totalRows = results.TotalRows
 	q.sentRows += uint64(len(results.Rows))
 	if q.sentRows > results.TotalRows {
 		return nil, nil
@@ -123,7 +555,443 @@ func (q *query) nextPage() ([][]interface{}, error) {
 // can be used before retrieving the iterator.
 func (q *query) Iter() Iter {
 	q.mode = iterMode
-	return &iter{q: q}
+	return &iter{q: q, mapper: q.nameMapper}
+}
+
+// All returns an iterator to retrieve all the rows of the query
+// resultset, one by one, fetching the pages as they are needed.
+// Using this method sets the query in "all" mode, that is,
+// the NextPage method can't be used after using All, but it
+// can be used before retrieving the iterator.
+func (q *query) All() RowIter {
+	q.mode = allMode
+	return &rowIter{iter{q: q, mapper: q.nameMapper}}
+}
+
+// Schema returns the fields of the schema of the query resultset. Each
+// field contains, among others, its name, type and mode.
+func (q *query) Schema() []*bigquery.TableFieldSchema {
+	return q.schema
+}
+
+// TotalRows returns the total number of rows in the query resultset,
+// across all pages, not just the rows in the pages retrieved. As a query
+// is always created once its job is complete, the total is always known.
+func (q *query) TotalRows() uint64 {
+	return q.totalRows
+}
+
+// PageCount returns the number of pages of the query resultset, with
+// pages of the page size of the query. If there is no page size, all
+// the rows are considered to be in a single page, although BigQuery may
+// still split them to keep the pages under its size limit. There is
+// always at least one page, even if the resultset is empty.
+func (q *query) PageCount() int {
+	if q.maxResults == 0 || q.totalRows == 0 {
+		return 1
+	}
+	return int((q.totalRows + q.maxResults - 1) / q.maxResults)
+}
+
+// CurrentPage returns the index of the page of the last rows retrieved,
+// starting at 0, computed from their offset in the resultset and the
+// page size of the query. Before any rows are retrieved, it is the page
+// at the start of the query.
+func (q *query) CurrentPage() int {
+	if q.maxResults == 0 {
+		return 0
+	}
+
+	// once all the rows are retrieved, the offset is past the last page
+	page := int(q.pageStart / q.maxResults)
+	if n := q.PageCount(); page >= n {
+		return n - 1
+	}
+	return page
+}
+
+// CacheHit reports whether the query results were served from the
+// query cache.
+func (q *query) CacheHit() bool {
+	return q.cacheHit
+}
+
+// JobID returns the ID of the BigQuery job that ran the query.
+func (q *query) JobID() string {
+	return q.jobID
+}
+
+// Location returns the geographic location where the job of the query
+// was run.
+func (q *query) Location() string {
+	return q.location
+}
+
+// Warnings returns the messages of the errors the query job completed
+// with, if any. These errors are not fatal, the job completed anyway,
+// but the results may be partial, e.g. if some of the data could not be
+// read. See the Strict field of the config to make them fail the query.
+func (q *query) Warnings() []string {
+	return q.warnings
+}
+
+// Errors returns the errors of the values of the rows retrieved so far
+// that could not be converted, in order, if the query was run with
+// WithLenientConversion. These values were set to their zero value. When
+// the rows are streamed, it must be called once the stream is done.
+func (q *query) Errors() []*ConversionError {
+	return q.conversionErrors
+}
+
+// cellErrors returns the function that records the conversion errors of the
+// row with the given index in the last page fetched if the conversion is
+// lenient, or nil otherwise.
+func (q *query) cellErrors(i int) cellErrorFunc {
+	if !q.lenient {
+		return nil
+	}
+
+	row := q.pageStart + uint64(i)
+	return func(column string, err error) {
+		q.conversionErrors = append(q.conversionErrors, &ConversionError{
+			Row:    row,
+			Column: column,
+			Err:    err,
+		})
+	}
+}
+
+// BytesProcessed returns the total number of bytes processed by the query.
+// The statistics of the query job are retrieved the first time they are
+// needed and reused afterwards.
+func (q *query) BytesProcessed() (int64, error) {
+	stats, err := q.statistics()
+	if err != nil {
+		return 0, err
+	}
+	return stats.TotalBytesProcessed, nil
+}
+
+// BytesBilled returns the total number of bytes billed for the query.
+// The statistics of the query job are retrieved the first time they are
+// needed and reused afterwards.
+func (q *query) BytesBilled() (int64, error) {
+	stats, err := q.statistics()
+	if err != nil {
+		return 0, err
+	}
+	return stats.TotalBytesBilled, nil
+}
+
+// TotalSlotMs returns the total number of slot-milliseconds consumed by
+// the query, which shows how much compute the query used regardless of
+// the bytes it processed. The statistics of the query job are retrieved
+// the first time they are needed and reused afterwards.
+func (q *query) TotalSlotMs() (int64, error) {
+	stats, err := q.statistics()
+	if err != nil {
+		return 0, err
+	}
+	return stats.TotalSlotMs, nil
+}
+
+// StartTime returns the time the query job started running, according to
+// BigQuery. The query job is retrieved the first time it is needed and
+// reused afterwards.
+func (q *query) StartTime() (time.Time, error) {
+	job, err := q.getJob()
+	if err != nil || job.Statistics == nil {
+		return time.Time{}, err
+	}
+	return msTime(job.Statistics.StartTime), nil
+}
+
+// EndTime returns the time the query job finished, according to
+// BigQuery. The query job is retrieved the first time it is needed and
+// reused afterwards.
+func (q *query) EndTime() (time.Time, error) {
+	job, err := q.getJob()
+	if err != nil || job.Statistics == nil {
+		return time.Time{}, err
+	}
+	return msTime(job.Statistics.EndTime), nil
+}
+
+// Duration returns the time it took BigQuery to run the query job, from
+// its start to its end, which does not include the time spent waiting for
+// the job to finish. The query job is retrieved the first time it is
+// needed and reused afterwards.
+func (q *query) Duration() (time.Duration, error) {
+	start, err := q.StartTime()
+	if err != nil || start.IsZero() {
+		return 0, err
+	}
+
+	end, err := q.EndTime()
+	if err != nil || end.IsZero() {
+		return 0, err
+	}
+	return end.Sub(start), nil
+}
+
+// PageToken returns an opaque token of the next page of results to be
+// retrieved, which can be given to QueryPage to resume the pagination of
+// the results later, even by another process. It is empty if there are
+// no more rows to retrieve.
+func (q *query) PageToken() string {
+	if q.initialRows != nil {
+		// the rows of the first page can only be requested by their start
+		return encodePageToken(q.sentRows, "")
+	}
+
+	if q.sentRows >= q.totalRows {
+		return ""
+	}
+	return encodePageToken(q.sentRows, q.pageToken)
+}
+
+// HasMorePages reports whether there are rows of the resultset after the
+// ones retrieved so far, that is, if the next page has any rows. The
+// rows after the max rows of the query, if any, are also taken into
+// account, although they are not retrieved and ErrRowsTruncated is
+// returned instead.
+func (q *query) HasMorePages() bool {
+	return len(q.initialRows) > 0 || q.sentRows < q.totalRows
+}
+
+// encodePageToken returns the token of the page at the given start of the
+// resultset with the given BigQuery page token, which is optional.
+func encodePageToken(start uint64, pageToken string) string {
+	token := strconv.FormatUint(start, 10) + ":" + pageToken
+	return base64.RawURLEncoding.EncodeToString([]byte(token))
+}
+
+// decodePageToken returns the start and the BigQuery page token of the page
+// with the given token.
+func decodePageToken(token string) (uint64, string, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, "", errInvalidPageToken
+	}
+
+	parts := strings.SplitN(string(data), ":", 2)
+	if len(parts) != 2 {
+		return 0, "", errInvalidPageToken
+	}
+
+	start, err := strconv.ParseUint(parts[0], 10, 64)
+	if err != nil {
+		return 0, "", errInvalidPageToken
+	}
+	return start, parts[1], nil
+}
+
+// NextPageQuery returns a new query, for the same job, that starts at the
+// page after the first page of this query, that is, its start plus its
+// max results, so pages can be navigated without any arithmetic. The
+// query must have been given its max results. ErrNoMorePages is returned
+// if there are no rows after the first page of this query.
+func (q *query) NextPageQuery() (Query, error) {
+	if q.maxResults == 0 {
+		return nil, errNoPageSize
+	}
+
+	start := q.start + q.maxResults
+	if start >= q.totalRows {
+		return nil, ErrNoMorePages
+	}
+
+	var pageToken string
+	if q.sentRows == start {
+		// only the first page has been read, so the page token is the one
+		// of the next page
+		pageToken = q.pageToken
+	}
+	return q.pageQuery(start, pageToken)
+}
+
+// PrevPageQuery returns a new query, for the same job, that starts at the
+// page before the first page of this query, that is, its start minus its
+// max results, or 0 if there are less rows before. The query must have
+// been given its max results. ErrNoPrevPage is returned if this query
+// starts at the beginning of the resultset.
+func (q *query) PrevPageQuery() (Query, error) {
+	if q.maxResults == 0 {
+		return nil, errNoPageSize
+	}
+
+	if q.start == 0 {
+		return nil, ErrNoPrevPage
+	}
+
+	var start uint64
+	if q.start > q.maxResults {
+		start = q.start - q.maxResults
+	}
+	return q.pageQuery(start, "")
+}
+
+// pageQuery returns a new query for the same job that starts at the given
+// start.
+func (q *query) pageQuery(start uint64, pageToken string) (Query, error) {
+	page, err := q.getQueryResults(q.ctx, start, pageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	query := newQuery(q.ctx, q.backend, page, q.projectID, start, q.maxResults)
+	query.retry = q.retry
+	query.nameMapper = q.nameMapper
+	query.maxRows = q.maxRows
+	query.lenient = q.lenient
+	return query, nil
+}
+
+// getQueryResults returns the page of results of the job of the query that
+// starts at the given start and has the given page token, retrying the
+// request according to the retry policy of the query.
+func (q *query) getQueryResults(ctx context.Context, start uint64, pageToken string) (*bigquery.GetQueryResultsResponse, error) {
+	var page *bigquery.GetQueryResultsResponse
+	err := q.retry.do(ctx, func() (err error) {
+		page, err = q.backend.GetQueryResults(
+			ctx,
+			q.projectID, q.jobID, q.location,
+			start, q.maxResults,
+			pageToken, 0,
+		)
+		return err
+	})
+	return page, err
+}
+
+// Stats returns all the statistics of the query job at once, e.g. for
+// audit logging. The statistics of the query job are retrieved the first
+// time they are needed and reused afterwards.
+func (q *query) Stats() (*QueryStatistics, error) {
+	stats, err := q.statistics()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &QueryStatistics{
+		BytesProcessed:     stats.TotalBytesProcessed,
+		BytesBilled:        stats.TotalBytesBilled,
+		SlotMs:             stats.TotalSlotMs,
+		CacheHit:           stats.CacheHit,
+		NumDmlAffectedRows: stats.NumDmlAffectedRows,
+	}
+
+	if job := q.job.Statistics; job != nil {
+		result.StartTime = msTime(job.StartTime)
+		result.EndTime = msTime(job.EndTime)
+	}
+	return result, nil
+}
+
+// QueryPlan returns the stages of the execution plan of the query, the
+// same ones shown in the execution details of the BigQuery console, e.g.
+// to check the performance of the query. The query job is retrieved the
+// first time it is needed and reused afterwards.
+func (q *query) QueryPlan() ([]QueryStage, error) {
+	stats, err := q.statistics()
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []QueryStage
+	for _, s := range stats.QueryPlan {
+		plan = append(plan, QueryStage{
+			ID:              s.Id,
+			Name:            s.Name,
+			Status:          s.Status,
+			RecordsRead:     s.RecordsRead,
+			RecordsWritten:  s.RecordsWritten,
+			WaitRatioAvg:    s.WaitRatioAvg,
+			WaitRatioMax:    s.WaitRatioMax,
+			ReadRatioAvg:    s.ReadRatioAvg,
+			ReadRatioMax:    s.ReadRatioMax,
+			ComputeRatioAvg: s.ComputeRatioAvg,
+			ComputeRatioMax: s.ComputeRatioMax,
+			WriteRatioAvg:   s.WriteRatioAvg,
+			WriteRatioMax:   s.WriteRatioMax,
+		})
+	}
+	return plan, nil
+}
+
+// msTime returns the time of the given milliseconds since the epoch, or the
+// zero time if there are none.
+func msTime(ms int64) time.Time {
+	if ms == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+// ResultTable returns the project, dataset and ID of the table the
+// results of the query were written to, which is an anonymous table that
+// expires after 24h, unless the query was given a destination table. It
+// can be queried directly or deleted early. The query job is retrieved
+// the first time it is needed and reused afterwards.
+func (q *query) ResultTable() (projectID, datasetID, tableID string, err error) {
+	job, err := q.getJob()
+	if err != nil {
+		return "", "", "", err
+	}
+
+	if job.Configuration == nil || job.Configuration.Query == nil ||
+		job.Configuration.Query.DestinationTable == nil {
+		return "", "", "", errors.New("the job of the query has no destination table")
+	}
+
+	table := job.Configuration.Query.DestinationTable
+	return table.ProjectId, table.DatasetId, table.TableId, nil
+}
+
+// ResultFingerprint returns a key that identifies the data of the results
+// of the query, made of the table of the results and the time it was
+// last modified, e.g. to detect when the results cached by an application
+// are stale. Running the same query again returns the same fingerprint
+// if its results are served from the query cache, see CacheHit, and a
+// different one if the data changed. The table is retrieved every time.
+func (q *query) ResultFingerprint() (string, error) {
+	projectID, datasetID, tableID, err := q.ResultTable()
+	if err != nil {
+		return "", err
+	}
+
+	table, err := q.backend.GetTable(q.ctx, projectID, datasetID, tableID)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s:%s.%s@%d", projectID, datasetID, tableID, table.LastModifiedTime), nil
+}
+
+// getJob returns the job of the query, which is retrieved only the first
+// time.
+func (q *query) getJob() (*bigquery.Job, error) {
+	if q.job == nil {
+		job, err := q.backend.GetJob(q.ctx, q.projectID, q.jobID, q.location)
+		if err != nil {
+			return nil, err
+		}
+		q.job = job
+	}
+	return q.job, nil
+}
+
+// statistics returns the query statistics of the job, which is retrieved
+// only the first time.
+func (q *query) statistics() (*bigquery.JobStatistics2, error) {
+	if _, err := q.getJob(); err != nil {
+		return nil, err
+	}
+
+	if q.job.Statistics == nil || q.job.Statistics.Query == nil {
+		return new(bigquery.JobStatistics2), nil
+	}
+	return q.job.Statistics.Query, nil
 }
 
 func transformRows(rows []*bigquery.TableRow) [][]interface{} {
@@ -1,9 +1,13 @@
 package bigq
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
 )
 
 func TestNextPage(t *testing.T) {
@@ -39,3 +43,467 @@ func TestNextPage(t *testing.T) {
 		}
 	}
 }
+
+func TestQuerySchema(t *testing.T) {
+	assert := assert.New(t)
+	fields := []*bigquery.TableFieldSchema{
+		{Name: "word", Type: "STRING", Mode: "NULLABLE"},
+		{Name: "word_count", Type: "INTEGER", Mode: "REQUIRED"},
+	}
+
+	q := newQuery(context.Background(), nil, &bigquery.GetQueryResultsResponse{
+		JobReference: &bigquery.JobReference{JobId: "foo", Location: "EU"},
+		Schema:       &bigquery.TableSchema{Fields: fields},
+		Rows: []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "zeal"}, {V: "5"}}},
+		},
+		TotalRows: 12,
+	}, "go-bigq", 0, 5)
+	assert.Equal(fields, q.Schema())
+	assert.Equal(uint64(12), q.TotalRows())
+	assert.Equal("foo", q.JobID())
+	assert.Equal("EU", q.Location())
+
+	rows, err := q.NextPage()
+	assert.Nil(err)
+	assert.Equal([][]interface{}{{"zeal", "5"}}, rows)
+}
+
+func TestQueryPageToken(t *testing.T) {
+	assert := assert.New(t)
+	page := &bigquery.GetQueryResultsResponse{
+		JobReference: &bigquery.JobReference{JobId: "foo"},
+		Rows: []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "zeal"}}},
+		},
+		PageToken: "token",
+		TotalRows: 12,
+	}
+
+	q := newQuery(context.Background(), nil, page, "go-bigq", 0, 1)
+	assert.Equal("token", q.pageToken)
+
+	page.Rows = nil
+	q = newQuery(context.Background(), nil, page, "go-bigq", 5, 1)
+	assert.Equal("", q.pageToken)
+}
+
+func TestServiceQuerySchema(t *testing.T) {
+	assert := assert.New(t)
+	service, err := New(WithConfigFile(tokenFile), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	})
+	assert.Nil(err)
+
+	q, err := service.Query(testQuery, 0, 5)
+	assert.Nil(err)
+
+	assert.Equal(uint64(20), q.TotalRows())
+	assert.NotEqual("", q.JobID())
+
+	schema := q.Schema()
+	assert.Equal(1, len(schema))
+	assert.Equal("word", schema[0].Name)
+	assert.Equal("STRING", schema[0].Type)
+}
+
+func TestQueryRows(t *testing.T) {
+	assert := assert.New(t)
+	q := newQuery(context.Background(), nil, &bigquery.GetQueryResultsResponse{
+		JobReference: &bigquery.JobReference{JobId: "foo"},
+		Schema: &bigquery.TableSchema{Fields: []*bigquery.TableFieldSchema{
+			{Name: "word", Type: "STRING"},
+			{Name: "word_count", Type: "INTEGER"},
+		}},
+		Rows: []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{{V: "zeal"}, {V: "5"}}},
+			{F: []*bigquery.TableCell{{V: "zed"}, {V: "1"}}},
+		},
+	}, "go-bigq", 0, 5)
+
+	rows, err := q.Rows()
+	assert.Nil(err)
+	assert.Equal([]map[string]interface{}{
+		{"word": "zeal", "word_count": int64(5)},
+		{"word": "zed", "word_count": int64(1)},
+	}, rows)
+}
+
+func TestQueryAppendRows(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{DefaultMaxResults: 2})
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+
+	buf := make([]map[string]interface{}, 0, 2)
+	buf, err = q.AppendRows(buf)
+	assert.Nil(err)
+	assert.Equal([]map[string]interface{}{{"n": int64(0)}, {"n": int64(1)}}, buf)
+	first := &buf[0]
+
+	buf, err = q.AppendRows(buf[:0])
+	assert.Nil(err)
+	assert.Equal([]map[string]interface{}{{"n": int64(2)}, {"n": int64(3)}}, buf)
+	assert.True(first == &buf[0], "the array of the slice is reused")
+
+	buf, err = q.AppendRows(buf)
+	assert.Nil(err)
+	assert.Len(buf, 3)
+	assert.Equal(int64(4), buf[2]["n"])
+
+	backend.rows[0].F[0].V = "foo"
+	q, err = service.Query(testQuery)
+	assert.Nil(err)
+
+	buf, err = q.AppendRows(buf)
+	assert.NotNil(err)
+	assert.Len(buf, 3)
+}
+
+func TestQueryForEach(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{DefaultMaxResults: 2})
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+
+	var sum int64
+	err = q.ForEach(func(row map[string]interface{}) error {
+		sum += row["n"].(int64)
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal(int64(10), sum)
+	assert.Equal(2, backend.calls["GetQueryResults"])
+
+	_, err = q.NextPage()
+	assert.Equal(errAlreadyReading, err)
+
+	q, err = service.Query(testQuery)
+	assert.Nil(err)
+
+	stop := errors.New("stop")
+	var seen int
+	err = q.ForEach(func(row map[string]interface{}) error {
+		seen++
+		return stop
+	})
+	assert.Equal(stop, err)
+	assert.Equal(1, seen)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	q, err = service.QueryContext(ctx, testQuery)
+	assert.Nil(err)
+
+	cancel()
+	err = q.ForEach(func(row map[string]interface{}) error { return nil })
+	assert.Equal(context.Canceled, err)
+}
+
+func TestQueryBytes(t *testing.T) {
+	assert := assert.New(t)
+	q := &query{job: &bigquery.Job{
+		Statistics: &bigquery.JobStatistics{
+			Query: &bigquery.JobStatistics2{
+				TotalBytesProcessed: 1024,
+				TotalBytesBilled:    10485760,
+				TotalSlotMs:         2048,
+			},
+		},
+	}}
+
+	processed, err := q.BytesProcessed()
+	assert.Nil(err)
+	assert.Equal(int64(1024), processed)
+
+	billed, err := q.BytesBilled()
+	assert.Nil(err)
+	assert.Equal(int64(10485760), billed)
+
+	slotMs, err := q.TotalSlotMs()
+	assert.Nil(err)
+	assert.Equal(int64(2048), slotMs)
+
+	q = &query{job: &bigquery.Job{}}
+	processed, err = q.BytesProcessed()
+	assert.Nil(err)
+	assert.Equal(int64(0), processed)
+}
+
+func TestQueryResultTable(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{})
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+
+	project, dataset, table, err := q.ResultTable()
+	assert.Nil(err)
+	assert.Equal("go-bigq", project)
+	assert.Equal("_anon", dataset)
+	assert.Equal("anonjob", table)
+
+	_, _, _, err = q.ResultTable()
+	assert.Nil(err)
+	assert.Equal(1, backend.calls["GetJob"])
+
+	q = &query{job: &bigquery.Job{}}
+	_, _, _, err = q.ResultTable()
+	assert.NotNil(err)
+}
+
+func TestQueryResultFingerprint(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.tables = map[string]*bigquery.Table{
+		"_anon.anonjob": {LastModifiedTime: 1500000000000},
+	}
+	service := newFakeService(backend, Config{})
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+
+	fingerprint, err := q.ResultFingerprint()
+	assert.Nil(err)
+	assert.Equal("go-bigq:_anon.anonjob@1500000000000", fingerprint)
+
+	backend.tables["_anon.anonjob"].LastModifiedTime++
+	changed, err := q.ResultFingerprint()
+	assert.Nil(err)
+	assert.NotEqual(fingerprint, changed)
+
+	delete(backend.tables, "_anon.anonjob")
+	_, err = q.ResultFingerprint()
+	assert.NotNil(err)
+}
+
+func TestQueryStats(t *testing.T) {
+	assert := assert.New(t)
+	q := &query{job: &bigquery.Job{
+		Statistics: &bigquery.JobStatistics{
+			StartTime: 1500000000000,
+			EndTime:   1500000002500,
+			Query: &bigquery.JobStatistics2{
+				TotalBytesProcessed: 1024,
+				TotalBytesBilled:    10485760,
+				TotalSlotMs:         2048,
+				CacheHit:            true,
+				NumDmlAffectedRows:  3,
+			},
+		},
+	}}
+
+	stats, err := q.Stats()
+	assert.Nil(err)
+	assert.Equal(&QueryStatistics{
+		StartTime:          time.Unix(1500000000, 0),
+		EndTime:            time.Unix(1500000002, 500000000),
+		BytesProcessed:     1024,
+		BytesBilled:        10485760,
+		SlotMs:             2048,
+		CacheHit:           true,
+		NumDmlAffectedRows: 3,
+	}, stats)
+
+	q = &query{job: &bigquery.Job{}}
+	stats, err = q.Stats()
+	assert.Nil(err)
+	assert.True(stats.StartTime.IsZero())
+}
+
+func TestQueryTimes(t *testing.T) {
+	assert := assert.New(t)
+	q := &query{job: &bigquery.Job{
+		Statistics: &bigquery.JobStatistics{
+			StartTime: 1500000000000,
+			EndTime:   1500000002500,
+		},
+	}}
+
+	start, err := q.StartTime()
+	assert.Nil(err)
+	assert.Equal(time.Unix(1500000000, 0), start)
+
+	end, err := q.EndTime()
+	assert.Nil(err)
+	assert.Equal(time.Unix(1500000002, 500000000), end)
+
+	d, err := q.Duration()
+	assert.Nil(err)
+	assert.Equal(2500*time.Millisecond, d)
+
+	q = &query{job: &bigquery.Job{
+		Statistics: &bigquery.JobStatistics{StartTime: 1500000000000},
+	}}
+	d, err = q.Duration()
+	assert.Nil(err)
+	assert.Equal(time.Duration(0), d)
+}
+
+func TestQueryPages(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{DefaultMaxResults: 2})
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+	assert.Equal(3, q.PageCount())
+	assert.Equal(0, q.CurrentPage())
+
+	var pages []int
+	for {
+		rows, err := q.NextPage()
+		assert.Nil(err)
+		if len(rows) == 0 {
+			break
+		}
+		pages = append(pages, q.CurrentPage())
+	}
+	assert.Equal([]int{0, 1, 2}, pages)
+	assert.Equal(2, q.CurrentPage())
+
+	q, err = service.QueryOpts(testQuery, WithOffset(4))
+	assert.Nil(err)
+	assert.Equal(2, q.CurrentPage())
+
+	q, err = service.QueryOpts(testQuery, WithPageSize(0))
+	assert.Nil(err)
+	assert.Equal(1, q.PageCount())
+	assert.Equal(0, q.CurrentPage())
+
+	q, err = newFakeService(newFakeBackend(0), Config{DefaultMaxResults: 2}).Query(testQuery)
+	assert.Nil(err)
+	assert.Equal(1, q.PageCount())
+	assert.Equal(0, q.CurrentPage())
+}
+
+func TestQueryHasMorePages(t *testing.T) {
+	assert := assert.New(t)
+	service := newFakeService(newFakeBackend(3), Config{DefaultMaxResults: 2})
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+	assert.True(q.HasMorePages())
+
+	_, err = q.NextPage()
+	assert.Nil(err)
+	assert.True(q.HasMorePages())
+
+	_, err = q.NextPage()
+	assert.Nil(err)
+	assert.False(q.HasMorePages())
+
+	q, err = service.QueryOpts(testQuery, WithOffset(2), WithPageSize(0))
+	assert.Nil(err)
+	assert.True(q.HasMorePages())
+
+	_, err = q.NextPage()
+	assert.Nil(err)
+	assert.False(q.HasMorePages())
+
+	q, err = newFakeService(newFakeBackend(0), Config{}).Query(testQuery)
+	assert.Nil(err)
+	assert.False(q.HasMorePages())
+}
+
+func TestQueryPlan(t *testing.T) {
+	assert := assert.New(t)
+	q := &query{job: &bigquery.Job{
+		Statistics: &bigquery.JobStatistics{
+			Query: &bigquery.JobStatistics2{
+				QueryPlan: []*bigquery.ExplainQueryStage{
+					{Id: 0, Name: "S00: Input", Status: "COMPLETE", RecordsRead: 100, RecordsWritten: 10, ReadRatioAvg: 0.5, ComputeRatioMax: 1},
+					{Id: 1, Name: "S01: Output", Status: "COMPLETE", RecordsRead: 10, RecordsWritten: 1, WaitRatioAvg: 0.25},
+				},
+			},
+		},
+	}}
+
+	plan, err := q.QueryPlan()
+	assert.Nil(err)
+	assert.Equal([]QueryStage{
+		{ID: 0, Name: "S00: Input", Status: "COMPLETE", RecordsRead: 100, RecordsWritten: 10, ReadRatioAvg: 0.5, ComputeRatioMax: 1},
+		{ID: 1, Name: "S01: Output", Status: "COMPLETE", RecordsRead: 10, RecordsWritten: 1, WaitRatioAvg: 0.25},
+	}, plan)
+
+	q = &query{job: &bigquery.Job{}}
+	plan, err = q.QueryPlan()
+	assert.Nil(err)
+	assert.Len(plan, 0)
+}
+
+func TestServiceQueryBytes(t *testing.T) {
+	assert := assert.New(t)
+	service, err := New(WithConfigFile(tokenFile), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	})
+	assert.Nil(err)
+
+	q, err := service.WithCache(false).Query(testQuery, 0, 5)
+	assert.Nil(err)
+
+	processed, err := q.BytesProcessed()
+	assert.Nil(err)
+	assert.True(processed > 0)
+
+	billed, err := q.BytesBilled()
+	assert.Nil(err)
+	assert.True(billed >= processed)
+}
+
+func TestQueryPageQueries(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{})
+
+	q, err := service.QueryOpts(testQuery, WithPageSize(2))
+	assert.Nil(err)
+
+	_, err = q.PrevPageQuery()
+	assert.Equal(ErrNoPrevPage, err)
+
+	var pages [][][]interface{}
+	for {
+		rows, err := q.NextPage()
+		assert.Nil(err)
+		pages = append(pages, rows)
+
+		q, err = q.NextPageQuery()
+		if err == ErrNoMorePages {
+			break
+		}
+		assert.Nil(err)
+	}
+
+	assert.Equal([][][]interface{}{
+		{{"0"}, {"1"}},
+		{{"2"}, {"3"}},
+		{{"4"}},
+	}, pages)
+
+	q, err = service.QueryOpts(testQuery, WithOffset(3), WithPageSize(2))
+	assert.Nil(err)
+
+	q, err = q.PrevPageQuery()
+	assert.Nil(err)
+	rows, err := q.NextPage()
+	assert.Nil(err)
+	assert.Equal([][]interface{}{{"1"}, {"2"}}, rows)
+
+	q, err = q.PrevPageQuery()
+	assert.Nil(err)
+	rows, err = q.NextPage()
+	assert.Nil(err)
+	assert.Equal([][]interface{}{{"0"}, {"1"}}, rows)
+
+	q, err = service.Query(testQuery)
+	assert.Nil(err)
+	_, err = q.NextPageQuery()
+	assert.Equal(errNoPageSize, err)
+}
@@ -0,0 +1,52 @@
+package bigq
+
+import "google.golang.org/api/bigquery/v2"
+
+// Result is a completed query with all the rows of its resultset, along with
+// the job that ran it and its statistics, so everything about the query can
+// be found in a single value.
+type Result struct {
+	// JobID is the ID of the BigQuery job that ran the query.
+	JobID string
+	// Schema are the fields of the schema of the resultset.
+	Schema []*bigquery.TableFieldSchema
+	// TotalRows is the total number of rows in the resultset, which is more
+	// than the number of rows if they were truncated.
+	TotalRows uint64
+	// Rows are the rows of the resultset as maps of column names to their
+	// values, converted the same way as in the Rows method of Query.
+	Rows []map[string]interface{}
+	// Stats are the statistics of the query job.
+	Stats *QueryStatistics
+}
+
+// QueryResult runs the given query with the given options, reads all the
+// rows of its resultset and returns them in a Result along with the
+// statistics of its job. As in QueryRows, all the rows are loaded in memory,
+// so queries with large resultsets should use Query and go through the
+// pages instead. If the rows are truncated to the max rows of the query, the
+// result is returned along with ErrRowsTruncated.
+func (s *Service) QueryResult(query string, opts ...QueryOption) (*Result, error) {
+	q, err := s.QueryOpts(query, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, rowsErr := allRows(q)
+	if rowsErr != nil && rowsErr != ErrRowsTruncated {
+		return nil, rowsErr
+	}
+
+	stats, err := q.Stats()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{
+		JobID:     q.JobID(),
+		Schema:    q.Schema(),
+		TotalRows: q.TotalRows(),
+		Rows:      rows,
+		Stats:     stats,
+	}, rowsErr
+}
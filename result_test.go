@@ -0,0 +1,33 @@
+package bigq
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServiceQueryResult(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.bytesBilled = 1024
+	service := newFakeService(backend, Config{DefaultMaxResults: 2})
+
+	result, err := service.QueryResult(testQuery)
+	assert.Nil(err)
+	assert.Equal("job", result.JobID)
+	assert.Equal(backend.schema.Fields, result.Schema)
+	assert.Equal(uint64(5), result.TotalRows)
+	assert.Len(result.Rows, 5)
+	assert.Equal(int64(4), result.Rows[4]["n"])
+	assert.Equal(int64(1024), result.Stats.BytesBilled)
+
+	result, err = service.QueryResult(testQuery, WithMaxRows(3))
+	assert.Equal(ErrRowsTruncated, err)
+	assert.Equal(uint64(5), result.TotalRows)
+	assert.Len(result.Rows, 3)
+
+	backend.queryErr = errors.New("invalid query")
+	_, err = service.QueryResult(testQuery)
+	assert.NotNil(err)
+}
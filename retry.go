@@ -0,0 +1,118 @@
+package bigq
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// RetryPolicy configures how the requests to BigQuery that failed are retried.
+// Only the requests that are safe to be retried are. The zero value of a
+// RetryPolicy does not retry any request.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of times a request will be retried.
+	MaxRetries int
+	// Backoff is the time to wait before the first retry. The time is doubled
+	// after every retry. By default, it is 1s.
+	Backoff time.Duration
+	// Jitter makes the time waited before every retry a random time between
+	// 0 and the backoff, instead of the backoff, so that the clients that
+	// failed at the same time, e.g. with "rateLimitExceeded", don't retry at
+	// the same time again.
+	Jitter bool
+	// ShouldRetry reports whether a request that failed with the given error
+	// should be retried. By default, IsTransientError is used.
+	ShouldRetry func(error) bool
+}
+
+const defaultRetryBackoff = time.Second
+
+var transientReasons = map[string]bool{
+	"backendError":      true,
+	"internalError":     true,
+	"rateLimitExceeded": true,
+}
+
+// IsTransientError reports whether the given error is a BigQuery API error
+// that is likely to be solved just by retrying the request, such as a
+// "backendError" or a "rateLimitExceeded".
+func IsTransientError(err error) bool {
+	apiErr, ok := err.(*googleapi.Error)
+	if !ok {
+		return false
+	}
+
+	for _, e := range apiErr.Errors {
+		if transientReasons[e.Reason] {
+			return true
+		}
+	}
+
+	switch apiErr.Code {
+	case http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (p RetryPolicy) enabled() bool {
+	return p.MaxRetries > 0
+}
+
+func (p RetryPolicy) shouldRetry(err error) bool {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(err)
+	}
+	return IsTransientError(err)
+}
+
+// do runs fn until it succeeds, fails with an error that should not be
+// retried or the max number of retries is reached.
+func (p RetryPolicy) do(ctx context.Context, fn func() error) error {
+	backoff := p.Backoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+
+	for retries := 0; ; retries++ {
+		err := fn()
+		if err == nil || retries >= p.MaxRetries || !p.shouldRetry(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(p.delay(backoff)):
+		}
+		backoff *= 2
+	}
+}
+
+// delay returns the time to wait before the retry with the given backoff.
+func (p RetryPolicy) delay(backoff time.Duration) time.Duration {
+	if !p.Jitter {
+		return backoff
+	}
+	return time.Duration(mathrand.Int63n(int64(backoff) + 1))
+}
+
+// randomID returns a random identifier with the format of an UUID.
+func randomID() (string, error) {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		return "", err
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
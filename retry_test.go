@@ -0,0 +1,136 @@
+package bigq
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/googleapi"
+)
+
+func TestIsTransientError(t *testing.T) {
+	cases := []struct {
+		err       error
+		transient bool
+	}{
+		{errors.New("foo"), false},
+		{apiError(400, "invalidQuery"), false},
+		{apiError(404, "notFound"), false},
+		{apiError(403, "rateLimitExceeded"), true},
+		{apiError(500, "backendError"), true},
+		{apiError(503, ""), true},
+	}
+
+	assert := assert.New(t)
+	for _, c := range cases {
+		assert.Equal(c.transient, IsTransientError(c.err), c.err.Error())
+	}
+}
+
+func TestRetryPolicy(t *testing.T) {
+	assert := assert.New(t)
+	policy := RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond}
+
+	var calls int
+	err := policy.do(context.Background(), func() error {
+		calls++
+		if calls < 2 {
+			return apiError(503, "backendError")
+		}
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal(2, calls)
+
+	calls = 0
+	err = policy.do(context.Background(), func() error {
+		calls++
+		return apiError(503, "backendError")
+	})
+	assert.NotNil(err)
+	assert.Equal(3, calls)
+
+	calls = 0
+	err = policy.do(context.Background(), func() error {
+		calls++
+		return apiError(400, "invalidQuery")
+	})
+	assert.NotNil(err)
+	assert.Equal(1, calls)
+}
+
+func TestRetryPolicyShouldRetry(t *testing.T) {
+	assert := assert.New(t)
+	errFoo := errors.New("foo")
+	policy := RetryPolicy{
+		MaxRetries: 3,
+		Backoff:    time.Millisecond,
+		ShouldRetry: func(err error) bool {
+			return err == errFoo
+		},
+	}
+
+	var calls int
+	err := policy.do(context.Background(), func() error {
+		calls++
+		return errFoo
+	})
+	assert.Equal(errFoo, err)
+	assert.Equal(4, calls)
+}
+
+func TestRetryPolicyJitter(t *testing.T) {
+	assert := assert.New(t)
+	backoff := time.Second
+	assert.Equal(backoff, RetryPolicy{}.delay(backoff))
+
+	policy := RetryPolicy{Jitter: true}
+	delays := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		d := policy.delay(backoff)
+		assert.True(d >= 0 && d <= backoff, d)
+		delays[d] = true
+	}
+	assert.True(len(delays) > 90, "the delays are not spread: %d different delays", len(delays))
+
+	var calls int
+	policy = RetryPolicy{MaxRetries: 2, Backoff: time.Millisecond, Jitter: true}
+	err := policy.do(context.Background(), func() error {
+		calls++
+		return apiError(503, "backendError")
+	})
+	assert.NotNil(err)
+	assert.Equal(3, calls)
+}
+
+func TestRetryPolicyDisabled(t *testing.T) {
+	assert := assert.New(t)
+	var calls int
+	err := RetryPolicy{}.do(context.Background(), func() error {
+		calls++
+		return apiError(503, "backendError")
+	})
+	assert.NotNil(err)
+	assert.Equal(1, calls)
+}
+
+func TestRandomID(t *testing.T) {
+	assert := assert.New(t)
+	id, err := randomID()
+	assert.Nil(err)
+	assert.Equal(36, len(id))
+
+	other, err := randomID()
+	assert.Nil(err)
+	assert.NotEqual(id, other)
+}
+
+func apiError(code int, reason string) error {
+	err := &googleapi.Error{Code: code, Message: reason}
+	if reason != "" {
+		err.Errors = []googleapi.ErrorItem{{Reason: reason}}
+	}
+	return err
+}
@@ -0,0 +1,241 @@
+package bigq
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"google.golang.org/api/bigquery/v2"
+)
+
+// scanRow converts the given row columns and copies them into the values
+// pointed at by dest. The fields of RECORD columns set to structs are named
+// with the given mapper, which is SnakeCase if it's nil. If onError is not
+// nil, the columns whose values can't be converted are reported to it and
+// set to their zero value instead of failing.
+func scanRow(mapper NameMapper, onError cellErrorFunc, schema []*bigquery.TableFieldSchema, row []interface{}, dest ...interface{}) error {
+	if len(dest) != len(row) {
+		return fmt.Errorf("expected %d destinations to scan the row, got %d", len(row), len(dest))
+	}
+
+	if len(schema) < len(row) {
+		return fmt.Errorf("the schema has %d fields but the row has %d columns", len(schema), len(row))
+	}
+
+	for i, cell := range row {
+		ptr := reflect.ValueOf(dest[i])
+		if ptr.Kind() != reflect.Ptr || ptr.IsNil() {
+			return fmt.Errorf("destination of type %T is not a non-nil pointer", dest[i])
+		}
+
+		if err := scanValue(mapper, schema[i], cell, ptr.Elem()); err != nil {
+			if onError == nil {
+				return fmt.Errorf("can't scan column %q: %s", schema[i].Name, err)
+			}
+			onError(schema[i].Name, err)
+			ptr.Elem().Set(reflect.Zero(ptr.Elem().Type()))
+		}
+	}
+
+	return nil
+}
+
+var stringType = reflect.TypeOf("")
+
+// scanValue converts the given cell value of the given field and sets it to
+// dst. NUMERIC and BIGNUMERIC values can also be set to strings, in which case
+// the value is set as it was returned by BigQuery. GEOGRAPHY values can also
+// be set to Geography values. REPEATED values can be set
+// to slices of any type their items can be set to and RECORD values to
+// structs or pointers to structs, whose fields are set like in scanStruct.
+func scanValue(mapper NameMapper, field *bigquery.TableFieldSchema, cell interface{}, dst reflect.Value) error {
+	if cell == nil {
+		return setValue(dst, nil)
+	}
+
+	if field.Mode == "REPEATED" && dst.Kind() == reflect.Slice {
+		return scanRepeated(mapper, field, cell, dst)
+	}
+
+	if isRecord(field) && field.Mode != "REPEATED" {
+		if dst.Kind() == reflect.Struct && !isScanner(dst) {
+			return scanRecord(mapper, field, cell, dst)
+		}
+
+		if dst.Kind() == reflect.Ptr && dst.Type().Elem().Kind() == reflect.Struct {
+			record := reflect.New(dst.Type().Elem())
+			if err := scanRecord(mapper, field, cell, record.Elem()); err != nil {
+				return err
+			}
+			dst.Set(record)
+			return nil
+		}
+	}
+
+	if raw, ok := cell.(string); ok && isNumeric(field) {
+		if dst.Type() == stringType || (dst.Kind() == reflect.Ptr && dst.Type().Elem() == stringType) {
+			return setValue(dst, raw)
+		}
+	}
+
+	if raw, ok := cell.(string); ok && isGeography(field) {
+		if dst.Type() == geographyType || (dst.Kind() == reflect.Ptr && dst.Type().Elem() == geographyType) {
+			return setValue(dst, Geography(raw))
+		}
+	}
+
+	v, err := convertValue(field, cell)
+	if err != nil {
+		return err
+	}
+	return setValue(dst, v)
+}
+
+func scanRepeated(mapper NameMapper, field *bigquery.TableFieldSchema, cell interface{}, dst reflect.Value) error {
+	items, ok := cell.([]interface{})
+	if !ok {
+		return fmt.Errorf("invalid value of type %T for repeated column %q", cell, field.Name)
+	}
+
+	item := *field
+	item.Mode = ""
+
+	slice := reflect.MakeSlice(dst.Type(), len(items), len(items))
+	for i, it := range items {
+		if err := scanValue(mapper, &item, cellValue(it), slice.Index(i)); err != nil {
+			return fmt.Errorf("can't set item %d: %s", i, err)
+		}
+	}
+
+	dst.Set(slice)
+	return nil
+}
+
+func scanRecord(mapper NameMapper, field *bigquery.TableFieldSchema, cell interface{}, dst reflect.Value) error {
+	obj, ok := cell.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("invalid value of type %T for record column %q", cell, field.Name)
+	}
+
+	cells, _ := obj["f"].([]interface{})
+	row := make([]interface{}, len(cells))
+	for i, c := range cells {
+		row[i] = cellValue(c)
+	}
+
+	return scanStructValue(mapper, nil, field.Fields, row, dst)
+}
+
+var scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+func isScanner(v reflect.Value) bool {
+	return reflect.PtrTo(v.Type()).Implements(scannerType)
+}
+
+const structTag = "bigquery"
+
+// scanStruct converts the given row columns and sets them to the fields of
+// the struct pointed at by dst with the same name as the columns. The name of
+// a field is the one in its "bigquery" tag or, if it doesn't have one, the
+// field name or the name given by the mapper, which are matched case
+// insensitively. If the mapper is nil, SnakeCase is used. Fields tagged with
+// "-" and unexported fields are ignored, and fields without a matching column
+// are left untouched. RECORD columns can be set to struct fields and REPEATED
+// columns to slice fields. If onError is not nil, the columns whose values
+// can't be converted are reported to it and their fields are set to their
+// zero value instead of failing.
+func scanStruct(mapper NameMapper, onError cellErrorFunc, schema []*bigquery.TableFieldSchema, row []interface{}, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("value of type %T is not a pointer to a struct", dst)
+	}
+
+	return scanStructValue(mapper, onError, schema, row, v.Elem())
+}
+
+func scanStructValue(mapper NameMapper, onError cellErrorFunc, schema []*bigquery.TableFieldSchema, row []interface{}, v reflect.Value) error {
+	fields := structFields(v.Type(), mapper)
+	for i, cell := range row {
+		if i >= len(schema) {
+			break
+		}
+
+		idx, ok := fields[strings.ToLower(schema[i].Name)]
+		if !ok {
+			continue
+		}
+
+		if err := scanValue(mapper, schema[i], cell, v.Field(idx)); err != nil {
+			if onError == nil {
+				return fmt.Errorf("can't set column %q to field %q: %s", schema[i].Name, v.Type().Field(idx).Name, err)
+			}
+			onError(schema[i].Name, err)
+			v.Field(idx).Set(reflect.Zero(v.Field(idx).Type()))
+		}
+	}
+
+	return nil
+}
+
+// structFields returns the index of the fields of the given struct type that
+// can be set by their lowercased column name, which is the one in the tag of
+// the field or both the field name and the name given by the mapper.
+func structFields(t reflect.Type, mapper NameMapper) map[string]int {
+	if mapper == nil {
+		mapper = SnakeCase
+	}
+
+	fields := make(map[string]int, t.NumField())
+	mapped := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			// unexported field
+			continue
+		}
+
+		name := field.Name
+		if tag := field.Tag.Get(structTag); tag == "-" {
+			continue
+		} else if tag != "" {
+			name = tag
+		} else if m := strings.ToLower(mapper(name)); m != "" {
+			mapped[m] = i
+		}
+
+		fields[strings.ToLower(name)] = i
+	}
+
+	// the names of the fields take precedence over the mapped ones
+	for name, i := range mapped {
+		if _, ok := fields[name]; !ok {
+			fields[name] = i
+		}
+	}
+	return fields
+}
+
+// NameMapper returns the name of the column that is set to the struct field
+// with the given name when the field has no tag.
+type NameMapper func(field string) string
+
+// SnakeCase is the NameMapper used by default, which returns the given field
+// name in snake case, e.g. "user_id" for UserID or "http_status" for
+// HTTPStatus.
+func SnakeCase(field string) string {
+	runes := []rune(field)
+	var sb strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				sb.WriteByte('_')
+			}
+		}
+		sb.WriteRune(unicode.ToLower(r))
+	}
+	return sb.String()
+}
@@ -0,0 +1,374 @@
+package bigq
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+var userSchema = []*bigquery.TableFieldSchema{
+	{Name: "user_id", Type: "INTEGER"},
+	{Name: "name", Type: "STRING"},
+	{Name: "age", Type: "INTEGER"},
+	{Name: "email", Type: "STRING"},
+}
+
+type user struct {
+	ID      int64 `bigquery:"user_id"`
+	Name    string
+	Age     *int64
+	Email   string `bigquery:"-"`
+	Missing string
+	email   string
+}
+
+func TestScanStruct(t *testing.T) {
+	assert := assert.New(t)
+	u := user{Email: "untouched", Missing: "untouched"}
+	err := scanStruct(nil, nil, userSchema, []interface{}{"1", "John", "42", "john@example.com"}, &u)
+	assert.Nil(err)
+	assert.Equal(int64(1), u.ID)
+	assert.Equal("John", u.Name)
+	assert.Equal(int64(42), *u.Age)
+	assert.Equal("untouched", u.Email)
+	assert.Equal("untouched", u.Missing)
+	assert.Equal("", u.email)
+}
+
+func TestScanStructNameMapper(t *testing.T) {
+	assert := assert.New(t)
+	schema := []*bigquery.TableFieldSchema{
+		{Name: "user_id", Type: "INTEGER"},
+		{Name: "full_name", Type: "STRING"},
+		{Name: "http_status", Type: "INTEGER"},
+		{Name: "USERNAME", Type: "STRING"},
+	}
+	row := []interface{}{"1", "John Doe", "200", "john"}
+
+	var u struct {
+		UserID     int64
+		FullName   string
+		HTTPStatus int64
+		Username   string
+	}
+	assert.Nil(scanStruct(nil, nil, schema, row, &u))
+	assert.Equal(int64(1), u.UserID)
+	assert.Equal("John Doe", u.FullName)
+	assert.Equal(int64(200), u.HTTPStatus)
+	assert.Equal("john", u.Username)
+
+	var prefixed struct {
+		ID   int64
+		Name string
+	}
+	mapper := func(field string) string {
+		return "user_" + field
+	}
+	assert.Nil(scanStruct(mapper, nil, schema[:1], row[:1], &prefixed))
+	assert.Equal(int64(1), prefixed.ID)
+}
+
+func TestSnakeCase(t *testing.T) {
+	cases := map[string]string{
+		"ID":         "id",
+		"UserID":     "user_id",
+		"Name":       "name",
+		"HTTPStatus": "http_status",
+		"CreatedAt":  "created_at",
+		"Address2":   "address2",
+		"V2Name":     "v2_name",
+		"name":       "name",
+	}
+
+	assert := assert.New(t)
+	for field, expected := range cases {
+		assert.Equal(expected, SnakeCase(field), field)
+	}
+}
+
+func TestScanStructGeography(t *testing.T) {
+	assert := assert.New(t)
+	schema := []*bigquery.TableFieldSchema{
+		{Name: "location", Type: "GEOGRAPHY"},
+		{Name: "store", Type: "RECORD", Fields: []*bigquery.TableFieldSchema{
+			{Name: "area", Type: "GEOGRAPHY"},
+			{Name: "entrances", Type: "GEOGRAPHY", Mode: "REPEATED"},
+		}},
+	}
+	row := []interface{}{
+		"POINT(-3.7 40.4)",
+		map[string]interface{}{"f": []interface{}{
+			map[string]interface{}{"v": "POLYGON((0 0, 1 0, 1 1, 0 0))"},
+			map[string]interface{}{"v": []interface{}{
+				map[string]interface{}{"v": "POINT(0 0)"},
+				map[string]interface{}{"v": "POINT(1 1)"},
+			}},
+		}},
+	}
+
+	var s struct {
+		Location Geography
+		Store    struct {
+			Area      *Geography
+			Entrances []string
+		}
+	}
+	assert.Nil(scanStruct(nil, nil, schema, row, &s))
+	assert.Equal(Geography("POINT(-3.7 40.4)"), s.Location)
+	assert.Equal(Geography("POLYGON((0 0, 1 0, 1 1, 0 0))"), *s.Store.Area)
+	assert.Equal([]string{"POINT(0 0)", "POINT(1 1)"}, s.Store.Entrances)
+
+	var wkt string
+	var store map[string]interface{}
+	assert.Nil(scanRow(nil, nil, schema, row, &wkt, &store))
+	assert.Equal("POINT(-3.7 40.4)", wkt)
+	assert.Equal(map[string]interface{}{
+		"area":      "POLYGON((0 0, 1 0, 1 1, 0 0))",
+		"entrances": []interface{}{"POINT(0 0)", "POINT(1 1)"},
+	}, store)
+}
+
+func TestScanBytes(t *testing.T) {
+	assert := assert.New(t)
+	data := []byte{0, 1, 0, 0xff, 'a', 0}
+	schema := []*bigquery.TableFieldSchema{
+		{Name: "data", Type: "BYTES"},
+		{Name: "chunks", Type: "BYTES", Mode: "REPEATED"},
+	}
+	row := []interface{}{
+		base64.StdEncoding.EncodeToString(data),
+		[]interface{}{
+			map[string]interface{}{"v": base64.StdEncoding.EncodeToString(data[:2])},
+			map[string]interface{}{"v": base64.StdEncoding.EncodeToString(data[2:])},
+		},
+	}
+
+	var b []byte
+	var chunks [][]byte
+	assert.Nil(scanRow(nil, nil, schema, row, &b, &chunks))
+	assert.Equal(data, b)
+	assert.Equal([][]byte{data[:2], data[2:]}, chunks)
+
+	var s struct {
+		Data *[]byte
+	}
+	assert.Nil(scanStruct(nil, nil, schema, row, &s))
+	assert.Equal(data, *s.Data)
+
+	assert.NotNil(scanRow(nil, nil, schema, []interface{}{"not base64!", nil}, &b, &chunks))
+}
+
+func TestScanStructInvalid(t *testing.T) {
+	assert := assert.New(t)
+	row := []interface{}{"1", "John", "42", "john@example.com"}
+
+	var u user
+	assert.NotNil(scanStruct(nil, nil, userSchema, row, u))
+
+	var i int
+	assert.NotNil(scanStruct(nil, nil, userSchema, row, &i))
+
+	var wrong struct {
+		Name int
+	}
+	assert.NotNil(scanStruct(nil, nil, userSchema, row, &wrong))
+}
+
+func TestScanRow(t *testing.T) {
+	assert := assert.New(t)
+	var (
+		id    int64
+		name  string
+		age   *int64
+		email interface{}
+	)
+	err := scanRow(nil, nil, userSchema, []interface{}{"1", "John", "42", "john@example.com"}, &id, &name, &age, &email)
+	assert.Nil(err)
+	assert.Equal(int64(1), id)
+	assert.Equal("John", name)
+	assert.Equal(int64(42), *age)
+	assert.Equal("john@example.com", email)
+
+	assert.NotNil(scanRow(nil, nil, userSchema, []interface{}{"1"}, &id, &name))
+	assert.NotNil(scanRow(nil, nil, userSchema[:1], []interface{}{"1", "John"}, &id, &name))
+}
+
+func TestScanStructNullable(t *testing.T) {
+	assert := assert.New(t)
+	schema := []*bigquery.TableFieldSchema{
+		{Name: "name", Type: "STRING", Mode: "NULLABLE"},
+		{Name: "age", Type: "INTEGER", Mode: "NULLABLE"},
+		{Name: "score", Type: "FLOAT", Mode: "NULLABLE"},
+	}
+
+	type row struct {
+		Name  sql.NullString
+		Age   *int64
+		Score sql.NullFloat64
+	}
+
+	var r row
+	assert.Nil(scanStruct(nil, nil, schema, []interface{}{"John", "42", "3.5"}, &r))
+	assert.Equal(sql.NullString{String: "John", Valid: true}, r.Name)
+	assert.Equal(int64(42), *r.Age)
+	assert.Equal(sql.NullFloat64{Float64: 3.5, Valid: true}, r.Score)
+
+	assert.Nil(scanStruct(nil, nil, schema, []interface{}{nil, nil, nil}, &r))
+	assert.Equal(sql.NullString{}, r.Name)
+	assert.Nil(r.Age)
+	assert.Equal(sql.NullFloat64{}, r.Score)
+
+	var notNullable struct {
+		Name string
+	}
+	assert.NotNil(scanStruct(nil, nil, schema, []interface{}{nil, nil, nil}, &notNullable))
+}
+
+func TestScanRowNullable(t *testing.T) {
+	assert := assert.New(t)
+	schema := []*bigquery.TableFieldSchema{
+		{Name: "name", Type: "STRING", Mode: "NULLABLE"},
+		{Name: "age", Type: "INTEGER", Mode: "NULLABLE"},
+	}
+
+	var (
+		name *string
+		age  sql.NullInt64
+	)
+	assert.Nil(scanRow(nil, nil, schema, []interface{}{"John", "42"}, &name, &age))
+	assert.Equal("John", *name)
+	assert.Equal(sql.NullInt64{Int64: 42, Valid: true}, age)
+
+	assert.Nil(scanRow(nil, nil, schema, []interface{}{nil, nil}, &name, &age))
+	assert.Nil(name)
+	assert.Equal(sql.NullInt64{}, age)
+
+	var v interface{} = "foo"
+	var s string
+	assert.Nil(scanRow(nil, nil, schema, []interface{}{nil, nil}, &v, &age))
+	assert.Nil(v)
+	assert.NotNil(scanRow(nil, nil, schema, []interface{}{nil, nil}, &s, &age))
+}
+
+func TestScanNumeric(t *testing.T) {
+	assert := assert.New(t)
+	const amount = "12345678901234567890123456789.123456789"
+	schema := []*bigquery.TableFieldSchema{
+		{Name: "amount", Type: "NUMERIC"},
+		{Name: "total", Type: "BIGNUMERIC"},
+	}
+	row := []interface{}{amount, amount}
+
+	var (
+		rat big.Rat
+		str string
+	)
+	assert.Nil(scanRow(nil, nil, schema, row, &rat, &str))
+	assert.Equal(amount, rat.FloatString(9))
+	assert.Equal(amount, str)
+
+	var s struct {
+		Amount *big.Rat
+		Total  *string
+	}
+	assert.Nil(scanStruct(nil, nil, schema, row, &s))
+	assert.Equal(amount, s.Amount.FloatString(9))
+	assert.Equal(amount, *s.Total)
+
+	var f float64
+	assert.NotNil(scanRow(nil, nil, schema, row, &f, &str))
+	assert.NotNil(scanRow(nil, nil, schema, row, rat, &str))
+}
+
+var orderSchema = []*bigquery.TableFieldSchema{
+	{Name: "customer", Type: "STRING"},
+	{Name: "orders", Type: "RECORD", Mode: "REPEATED", Fields: []*bigquery.TableFieldSchema{
+		{Name: "id", Type: "INTEGER"},
+		{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+		{Name: "items", Type: "RECORD", Mode: "REPEATED", Fields: []*bigquery.TableFieldSchema{
+			{Name: "sku", Type: "STRING"},
+			{Name: "qty", Type: "INTEGER"},
+		}},
+	}},
+	{Name: "address", Type: "RECORD", Fields: []*bigquery.TableFieldSchema{
+		{Name: "city", Type: "STRING"},
+	}},
+}
+
+func record(values ...interface{}) map[string]interface{} {
+	var cells []interface{}
+	for _, v := range values {
+		cells = append(cells, map[string]interface{}{"v": v})
+	}
+	return map[string]interface{}{"f": cells}
+}
+
+func repeated(values ...interface{}) []interface{} {
+	items := []interface{}{}
+	for _, v := range values {
+		items = append(items, map[string]interface{}{"v": v})
+	}
+	return items
+}
+
+var orderRow = []interface{}{
+	"John",
+	repeated(
+		record("1", repeated("fast", "gift"), repeated(
+			record("A", "2"),
+			record("B", "1"),
+		)),
+		record("2", repeated(), repeated(
+			record("C", "5"),
+		)),
+	),
+	record("Madrid"),
+}
+
+func TestScanStructNested(t *testing.T) {
+	type item struct {
+		SKU string
+		Qty int64
+	}
+
+	type order struct {
+		ID    int64
+		Tags  []string
+		Items []item
+	}
+
+	var customer struct {
+		Customer string
+		Orders   []order
+		Address  *struct {
+			City string
+		}
+	}
+
+	assert := assert.New(t)
+	assert.Nil(scanStruct(nil, nil, orderSchema, orderRow, &customer))
+	assert.Equal("John", customer.Customer)
+	assert.Equal([]order{
+		{ID: 1, Tags: []string{"fast", "gift"}, Items: []item{{"A", 2}, {"B", 1}}},
+		{ID: 2, Tags: []string{}, Items: []item{{"C", 5}}},
+	}, customer.Orders)
+	assert.Equal("Madrid", customer.Address.City)
+
+	var generic struct {
+		Orders  []map[string]interface{}
+		Address map[string]interface{}
+	}
+	assert.Nil(scanStruct(nil, nil, orderSchema, orderRow, &generic))
+	assert.Equal(2, len(generic.Orders))
+	assert.Equal([]interface{}{"fast", "gift"}, generic.Orders[0]["tags"])
+	assert.Equal(map[string]interface{}{"city": "Madrid"}, generic.Address)
+
+	var wrong struct {
+		Orders []int64
+	}
+	assert.NotNil(scanStruct(nil, nil, orderSchema, orderRow, &wrong))
+}
@@ -1,11 +1,16 @@
 package bigq
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 	"time"
 
 	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
 )
 
 // Config has some parameters that are needed for the configuration of the
@@ -13,20 +18,319 @@ import (
 type Config struct {
 	DatasetID string
 	ProjectID string
+	// BillingProjectID is the project the jobs of the queries are run in,
+	// and billed to, if it's not the ProjectID, e.g. to bill all the jobs to
+	// a central project while reading the data of another one. The default
+	// dataset is still the one of the ProjectID. By default, the jobs are
+	// run in the ProjectID.
+	BillingProjectID string
+	// Dialect is the SQL dialect of the queries. By default, queries are
+	// run using standard SQL.
+	Dialect Dialect
+	// PollInterval is the time to wait between checks of the status of a
+	// job that is not complete yet, such as the job of a statement run with
+	// Execute. The jobs of queries are waited for by BigQuery itself, see
+	// ServerTimeout. By default, it is 300ms.
+	PollInterval time.Duration
+	// MaxPollInterval enables an exponential backoff of the polling interval.
+	// If it's set, the polling interval is doubled after every check until it
+	// reaches MaxPollInterval. By default, the polling interval is constant.
+	MaxPollInterval time.Duration
+	// RetryPolicy is the policy used to retry the requests to BigQuery that
+	// failed, including the requests of the pages of results of the queries.
+	// By default, requests are not retried.
+	RetryPolicy RetryPolicy
+	// Priority is the priority of the queries. By default, queries are run
+	// with interactive priority.
+	Priority Priority
+	// UseCache sets whether the queries can be served from the query cache.
+	// If it's nil, the BigQuery default is used, which is to use the cache.
+	UseCache *bool
+	// ServerTimeout is how long BigQuery waits for a query to complete before
+	// responding to the request that runs it. Queries that complete within
+	// this time return their results right away, the rest are waited for by
+	// requesting their results, with BigQuery waiting up to this time again
+	// on every request. This timeout is unrelated to the polling done by the
+	// client. By default, the BigQuery default is used to run the queries
+	// and 10s to wait for them. It can be combined with MaxQueryDuration to
+	// separate how long a query can take to return its first results, the
+	// server timeout, and how long it can take overall, in which case the
+	// server timeout never exceeds what is left of the max query duration.
+	ServerTimeout time.Duration
+	// Logger receives the events of the queries, such as their submission
+	// and every poll of their jobs. By default, events are discarded.
+	Logger Logger
+	// Metrics collects the metrics of the queries, such as their duration
+	// and the bytes billed. By default, NopMetricsCollector is used.
+	Metrics MetricsCollector
+	// Labels are attached to the jobs of all the queries, e.g. to attribute
+	// their cost in the billing exports. They can be extended or overridden
+	// for some queries using WithLabels.
+	Labels map[string]string
+	// ConnectionProperties are the connection properties the queries are run
+	// with, such as "time_zone", which sets the default time zone of the date
+	// and time functions. They are given to BigQuery as they are, and they
+	// can be extended or overridden for some queries using
+	// WithConnectionProperties.
+	ConnectionProperties map[string]string
+	// MaxBytesBilled limits the bytes billed for every query. Queries that
+	// would bill more bytes fail without being run, with a
+	// BytesBilledLimitError. By default, there is no limit other than the
+	// one of the project.
+	MaxBytesBilled int64
+	// DefaultMaxResults is the max number of results per page of the
+	// queries run without giving their max results. By default, the
+	// BigQuery default is used.
+	DefaultMaxResults uint64
+	// MaxRows is the max number of rows retrieved from the resultsets of the
+	// queries run without giving their max rows, as a safeguard of the
+	// memory used regardless of the rows returned by the queries. Once the
+	// max rows of a query are retrieved, no more pages are fetched and
+	// ErrRowsTruncated is returned if there are more rows. By default, all
+	// the rows can be retrieved.
+	MaxRows uint64
+	// Location is the geographic location where the jobs of the queries are
+	// run, which must be the location of the datasets used, e.g. "EU" or
+	// "asia-northeast1". By default, it is empty, which means the US
+	// multi-region, unless BigQuery can find out the location from the
+	// query.
+	Location string
+	// MaxQueryDuration limits the time a query can take, since it is
+	// submitted until its job is done. Queries that take longer fail with
+	// ErrQueryTimeout and their jobs are cancelled. By default, queries can
+	// take as long as BigQuery allows, unless the context given to run them
+	// has a deadline.
+	MaxQueryDuration time.Duration
+	// Strict makes the queries whose jobs complete with errors fail with a
+	// JobError with those errors, even if the jobs did not fail, e.g. when
+	// only some of the data could be read. By default, these errors are
+	// available as the warnings of the queries.
+	Strict bool
+	// MaxConcurrentQueries limits the number of queries run at the same time
+	// by the service and the services derived from it, e.g. with WithLabels,
+	// to stay within the concurrent queries quota of the project. Queries
+	// wait for a free slot before being submitted, unless their context is
+	// done. By default, it is 0, which means the queries are not limited.
+	MaxConcurrentQueries int
+	// JobIDPrefix is the prefix of the IDs of the jobs inserted by the
+	// service, which is followed by a random suffix to keep them unique,
+	// e.g. to embed the ID of a request to trace its jobs. As the ID of the
+	// jobs of queries can only be given when they are inserted as jobs,
+	// if there is a prefix, queries are always inserted as jobs and their
+	// first page of results is fetched once their job is done. By default,
+	// the IDs are random.
+	JobIDPrefix string
+	// NameMapper returns the name of the column set to the struct fields
+	// without a tag in Iter.ScanStruct and QueryInto, which are matched by
+	// their field name as well. By default, SnakeCase is used, so UserID is
+	// set from the user_id column.
+	NameMapper NameMapper
 }
 
+// Priority is the priority a query is run with.
+type Priority string
+
+const (
+	// PriorityInteractive runs the queries as soon as possible. Interactive
+	// queries count towards the concurrent rate limit of the project.
+	PriorityInteractive Priority = "INTERACTIVE"
+	// PriorityBatch queues the queries to be run as soon as there are idle
+	// resources available, which usually takes a few minutes. Batch queries
+	// don't count towards the concurrent rate limit of the project. As the
+	// queries need to be inserted as jobs instead of running them directly,
+	// the first page of results is always fetched once the job is done.
+	PriorityBatch Priority = "BATCH"
+)
+
+const defaultPollInterval = 300 * time.Millisecond
+
+func (c Config) pollInterval() time.Duration {
+	if c.PollInterval <= 0 {
+		return defaultPollInterval
+	}
+	return c.PollInterval
+}
+
+// defaultWaitTimeout is how long BigQuery waits for a query job to complete
+// before responding to the requests of its results, if there is no server
+// timeout.
+const defaultWaitTimeout = 10 * time.Second
+
+func (c Config) waitTimeout() time.Duration {
+	if c.ServerTimeout <= 0 {
+		return defaultWaitTimeout
+	}
+	return c.ServerTimeout
+}
+
+// nextPollInterval returns the interval to wait after having waited for the
+// given interval in the previous check.
+func (c Config) nextPollInterval(interval time.Duration) time.Duration {
+	if interval >= c.MaxPollInterval {
+		return interval
+	}
+
+	interval *= 2
+	if interval > c.MaxPollInterval {
+		return c.MaxPollInterval
+	}
+	return interval
+}
+
+// Dialect is the SQL dialect used to run the queries.
+type Dialect int
+
+const (
+	// DialectStandard is the standard SQL dialect. It is the default one.
+	DialectStandard Dialect = iota
+	// DialectLegacy is the legacy BigQuery SQL dialect. Parameterized queries
+	// are not supported using this dialect.
+	DialectLegacy
+)
+
+// Querier runs queries in BigQuery. It is implemented by Service, so code
+// that runs queries can depend on a Querier and be given a fake one in its
+// tests.
+type Querier interface {
+	// Query creates a new query with the SQL sentence passed and a series of
+	// arguments, the start and the max results per page.
+	Query(query string, args ...uint64) (Query, error)
+
+	// QueryContext is like Query but the given context is used for all the
+	// requests made to BigQuery.
+	QueryContext(ctx context.Context, query string, args ...uint64) (Query, error)
+
+	// QueryOpts is like Query but the start and the max results per page
+	// are given as options.
+	QueryOpts(query string, opts ...QueryOption) (Query, error)
+
+	// QueryWithParams is like Query but the given parameters are bound to the
+	// named parameters used in the SQL sentence.
+	QueryWithParams(query string, params map[string]interface{}, args ...uint64) (Query, error)
+
+	// QueryWithArgs is like Query but the given parameters are bound, in
+	// order, to the positional parameters used in the SQL sentence.
+	QueryWithArgs(query string, params ...interface{}) (Query, error)
+
+	// QueryRows runs the given query and returns all the rows in its
+	// resultset as maps of column names to their values.
+	QueryRows(query string) ([]map[string]interface{}, error)
+
+	// QueryResult runs the given query and returns all the rows in its
+	// resultset along with its schema, job and statistics.
+	QueryResult(query string, opts ...QueryOption) (*Result, error)
+
+	// DryRun validates the given SQL sentence without running it and returns
+	// the statistics of the query that would be run.
+	DryRun(query string) (*QueryStats, error)
+
+	// QueryWithBudget is like Query but the query is only run if a dry run
+	// of the query estimates it would not process more than the given bytes.
+	QueryWithBudget(query string, maxBytes uint64) (Query, error)
+
+	// Validate validates the given SQL sentence in BigQuery without running
+	// it.
+	Validate(query string) error
+
+	// QueryToTable is like Query but the results are written to the given
+	// table instead of a temporary one.
+	QueryToTable(query, destTable string, opts ...TableOption) (Query, error)
+
+	// Execute runs the given DML or DDL statement and returns its result.
+	Execute(statement string) (*ExecResult, error)
+
+	// SubmitQuery inserts a job that runs the given SQL sentence and returns
+	// its ID right away, without waiting for it to finish.
+	SubmitQuery(query string) (string, error)
+
+	// WaitForResults waits for the query job with the given ID to finish and
+	// returns the query with its results.
+	WaitForResults(jobID string, args ...uint64) (Query, error)
+
+	// GetQuery returns the query with the results of the existing query job
+	// with the given ID, without waiting for it.
+	GetQuery(jobID string, args ...uint64) (Query, error)
+}
+
+var _ Querier = (*Service)(nil)
+
 // Service instances will be able to make queries. A Service is basically
 // a Query constructor that holds the connection with BigQuery.
 type Service struct {
 	config  Config
-	service *bigquery.Service
+	backend backend
+	// datasetProjectID is the project of the default dataset, if it's not
+	// the project of the config.
+	datasetProjectID string
+	// slots limits the concurrent queries, if they are limited. It is shared
+	// by the derived services.
+	slots chan struct{}
+	// closed is closed once the service is closed, along with the derived
+	// services, which share it.
+	closed    chan struct{}
+	closeOnce *sync.Once
+	// jobs are the jobs the service is waiting for, so they can be cancelled
+	// with CancelAll. They are shared by the derived services.
+	jobs *activeJobs
+	// inserters are the inserters of the service and the derived services
+	// that are not closed yet, so they are closed along with the service.
+	inserters *openInserters
+}
+
+// activeJobs are the jobs a service and its derived services are waiting for.
+type activeJobs struct {
+	mu sync.Mutex
+	// refs are the references of the jobs. Every wait has its own
+	// reference, even if the same job is waited for more than once.
+	refs map[*bigquery.JobReference]struct{}
 }
 
-var errInvalidConfig = errors.New("dataset and project can not be empty")
+var (
+	errInvalidConfig = errors.New("dataset and project can not be empty")
+	errLegacyParams  = errors.New("query parameters can not be used with the legacy SQL dialect")
+
+	// ErrJobNotDone is returned when the results of a job that is still
+	// running are requested.
+	ErrJobNotDone = errors.New("the job is not done yet")
+
+	// ErrOffsetOutOfRange is returned when the start of a query is beyond
+	// the last row of its resultset.
+	ErrOffsetOutOfRange = errors.New("the start of the query is beyond the end of the resultset")
+
+	// ErrEmptyQuery is returned when the query to run is empty or only
+	// contains whitespace.
+	ErrEmptyQuery = errors.New("the query can not be empty")
+
+	// ErrQueryTimeout is returned when a query takes longer than the
+	// MaxQueryDuration of the config.
+	ErrQueryTimeout = errors.New("the query took longer than the max query duration")
+
+	// ErrRowsTruncated is returned when the max rows of a query were
+	// retrieved and there are more rows in its resultset, which are not
+	// retrieved.
+	ErrRowsTruncated = errors.New("the rows of the query were truncated to its max rows")
+
+	// ErrServiceClosed is returned when a query is run with a service that
+	// has been closed.
+	ErrServiceClosed = errors.New("the service is closed")
+
+	// ErrBudgetExceeded is returned when a query run with QueryWithBudget
+	// would process more bytes than its budget.
+	ErrBudgetExceeded = errors.New("the query would process more bytes than its budget")
+)
 
 // New creates a new Service with the given client options and config.
 func New(clientOptions ClientOptions, config Config) (*Service, error) {
-	bqService, err := clientOptions.Service()
+	var (
+		backend = new(serviceBackend)
+		err     error
+	)
+	if opt, ok := clientOptions.(clientOption); ok {
+		// the HTTP client is only known with the options of this package
+		backend.service, backend.client, err = newServiceClient(opt)
+	} else {
+		backend.service, err = clientOptions.Service()
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -35,7 +339,145 @@ func New(clientOptions ClientOptions, config Config) (*Service, error) {
 		return nil, errInvalidConfig
 	}
 
-	return &Service{config, bqService}, nil
+	return newWithBackend(config, backend), nil
+}
+
+// newWithBackend creates a new Service with the given config that makes the
+// requests with the given backend.
+func newWithBackend(config Config, backend backend) *Service {
+	s := &Service{
+		config:    config,
+		backend:   backend,
+		closed:    make(chan struct{}),
+		closeOnce: new(sync.Once),
+		jobs:      &activeJobs{refs: make(map[*bigquery.JobReference]struct{})},
+		inserters: &openInserters{all: make(map[*BufferedInserter]struct{})},
+	}
+	if config.MaxConcurrentQueries > 0 {
+		s.slots = make(chan struct{}, config.MaxConcurrentQueries)
+	}
+	return s
+}
+
+// Raw returns the underlying BigQuery client service, as an escape hatch to
+// make the requests this package doesn't support without constructing
+// another client. Use it at your own risk: the requests made with it bypass
+// the retry policy, the logger, the metrics and the rest of the config of
+// the service.
+func (s *Service) Raw() *bigquery.Service {
+	if b, ok := s.backend.(*serviceBackend); ok {
+		return b.service
+	}
+	return nil
+}
+
+// Close closes the service and all the services derived from it, so their
+// queries are not run anymore and fail with ErrServiceClosed, including the
+// ones waiting for a free slot to run. The queries already running are not
+// canceled, see CancelAll to cancel them. The inserters created with
+// NewInserter that are not closed yet are closed, so their buffered rows are
+// inserted, and the error of the first one that fails is returned. The idle
+// connections of the HTTP client of the service are closed, if the client is
+// known, that is, if the client options are the ones of this package. It is
+// safe to call Close more than once.
+func (s *Service) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		close(s.closed)
+		err = s.inserters.closeAll()
+		if b, ok := s.backend.(*serviceBackend); ok && b.client != nil {
+			b.client.CloseIdleConnections()
+		}
+	})
+	return err
+}
+
+// CancelAll requests the cancellation of all the jobs the service and the
+// services derived from it are waiting for, such as the ones of the queries
+// that are running, e.g. to not leave them running on a graceful shutdown.
+// The jobs submitted with SubmitQuery are only cancelled while their results
+// are being waited for. The cancellation of every job is requested even if
+// some of the requests fail, in which case the first error is returned. As
+// in CancelJob, the jobs may still be running right after the requests, and
+// it's safe to cancel the jobs that completed in the meantime.
+func (s *Service) CancelAll(ctx context.Context) error {
+	if s.jobs == nil {
+		return nil
+	}
+
+	s.jobs.mu.Lock()
+	refs := make([]*bigquery.JobReference, 0, len(s.jobs.refs))
+	for ref := range s.jobs.refs {
+		refs = append(refs, ref)
+	}
+	s.jobs.mu.Unlock()
+
+	var firstErr error
+	for _, ref := range refs {
+		err := s.config.RetryPolicy.do(ctx, func() error {
+			_, err := s.backend.CancelJob(ctx, ref.ProjectId, ref.JobId, ref.Location)
+			return err
+		})
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// trackJob adds the given job to the jobs the service is waiting for until
+// the returned function is called, once the wait is over.
+func (s *Service) trackJob(jobID string) func() {
+	if s.jobs == nil {
+		return func() {}
+	}
+
+	ref := &bigquery.JobReference{
+		JobId:     jobID,
+		ProjectId: s.jobProject(),
+		Location:  s.config.Location,
+	}
+
+	s.jobs.mu.Lock()
+	s.jobs.refs[ref] = struct{}{}
+	s.jobs.mu.Unlock()
+
+	return func() {
+		s.jobs.mu.Lock()
+		delete(s.jobs.refs, ref)
+		s.jobs.mu.Unlock()
+	}
+}
+
+// acquireSlot waits until there is a free slot to run a query, if the
+// concurrent queries are limited, or the given context is done. It fails if
+// the service is closed.
+func (s *Service) acquireSlot(ctx context.Context) error {
+	select {
+	case <-s.closed:
+		return ErrServiceClosed
+	default:
+	}
+
+	if s.slots == nil {
+		return nil
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-s.closed:
+		return ErrServiceClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// releaseSlot frees the slot acquired to run a query.
+func (s *Service) releaseSlot() {
+	if s.slots != nil {
+		<-s.slots
+	}
 }
 
 // Query creates a new query with the SQL sentence passed and a series of
@@ -43,71 +485,828 @@ func New(clientOptions ClientOptions, config Config) (*Service, error) {
 // parameter passed will be the start, that is, the offset in the resultset.
 // The second parameter passed will be the max results allowed per page.
 func (s *Service) Query(query string, args ...uint64) (Query, error) {
-	start, maxResults, err := queryArgs(args...)
+	return s.QueryContext(context.Background(), query, args...)
+}
+
+// WithCache returns a copy of the service, using the same connection, that
+// runs its queries with the query cache enabled or disabled, regardless of
+// the UseCache setting of its config.
+func (s *Service) WithCache(use bool) *Service {
+	svc := *s
+	svc.config.UseCache = &use
+	return &svc
+}
+
+// WithDataset returns a copy of the service, using the same connection, that
+// runs its queries with the given dataset as the default dataset, that is, the
+// dataset of the tables that are not fully qualified in the queries. The
+// project of the dataset stays the same. If the dataset is empty, queries
+// have no default dataset, so all their tables must be fully qualified.
+func (s *Service) WithDataset(datasetID string) *Service {
+	svc := *s
+	svc.config.DatasetID = datasetID
+	return &svc
+}
+
+// WithProjectDataset is like WithDataset, but the default dataset belongs to
+// the given project, which may not be the project of the service. Queries are
+// still run, and billed, in the project of the service, so this can be used to
+// query the datasets of other projects.
+func (s *Service) WithProjectDataset(projectID, datasetID string) *Service {
+	svc := s.WithDataset(datasetID)
+	svc.datasetProjectID = projectID
+	return svc
+}
+
+// QueryContext is like Query but the given context is used for all the
+// requests made to BigQuery, including the polling while waiting for the job
+// to finish and the retrieval of the result pages. If the context is cancelled
+// while waiting, the context error is returned.
+func (s *Service) QueryContext(ctx context.Context, query string, args ...uint64) (Query, error) {
+	opts, err := s.queryArgs(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.query(ctx, s.newQueryRequest(query), nil, opts)
+}
+
+// QueryOpts is like Query but the start and the max results per page are
+// given as options, e.g. WithOffset and WithPageSize, instead of arguments.
+func (s *Service) QueryOpts(query string, opts ...QueryOption) (Query, error) {
+	return s.query(context.Background(), s.newQueryRequest(query), nil, s.queryOptions(opts...))
+}
+
+// QueryWithParams is like Query but the given parameters are bound to the
+// named parameters used in the SQL sentence, e.g. the value with the key
+// "userId" will be bound to @userId. Supported parameter types are string,
+// int, int64, float64, bool, time.Time and []byte, slices of them, which are
+// bound as ARRAY values, e.g. for `WHERE id IN UNNEST(@ids)`, and structs,
+// which are bound as STRUCT values. Queries with parameters
+// require the standard SQL dialect and named parameters can't be mixed with
+// positional parameters in the same query.
+func (s *Service) QueryWithParams(query string, params map[string]interface{}, args ...uint64) (Query, error) {
+	if s.config.Dialect == DialectLegacy {
+		return nil, errLegacyParams
+	}
+
+	if _, positional := paramStyles(query); positional {
+		return nil, errMixedParams
+	}
+
+	queryParams, err := namedParams(params)
 	if err != nil {
 		return nil, err
 	}
 
-	resp, err := s.requestQuery(query, maxResults)
+	opts, err := s.queryArgs(args...)
 	if err != nil {
 		return nil, err
 	}
 
-	if !resp.JobComplete {
-		if err := s.waitForJob(resp.JobReference.JobId); err != nil {
+	req := s.newQueryRequest(query)
+	req.ParameterMode = namedParameterMode
+	req.QueryParameters = queryParams
+	return s.query(context.Background(), req, nil, opts)
+}
+
+// QueryWithArgs is like Query but the given parameters are bound, in order, to
+// the positional parameters (?) used in the SQL sentence. Supported parameter
+// types are the same as in QueryWithParams. Queries with parameters require the
+// standard SQL dialect and positional parameters can't be mixed with named
+// parameters in the same query. As all the arguments are parameters, the query
+// starts at the beginning of the resultset and uses the default max results per
+// page.
+func (s *Service) QueryWithArgs(query string, params ...interface{}) (Query, error) {
+	if s.config.Dialect == DialectLegacy {
+		return nil, errLegacyParams
+	}
+
+	if named, _ := paramStyles(query); named {
+		return nil, errMixedParams
+	}
+
+	queryParams, err := positionalParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	req := s.newQueryRequest(query)
+	req.ParameterMode = positionalParameterMode
+	req.QueryParameters = queryParams
+	return s.query(context.Background(), req, nil, s.queryOptions())
+}
+
+// QueryRows runs the given query and returns all the rows in its resultset
+// as maps of column names to their values converted to Go types, like the
+// Rows method of Query does. Note that all the rows are loaded in memory, so
+// queries with large resultsets should use Query and iterate through the
+// results instead. If the rows are truncated to the MaxRows of the config,
+// the rows retrieved are returned along with ErrRowsTruncated.
+func (s *Service) QueryRows(query string) ([]map[string]interface{}, error) {
+	q, err := s.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	return allRows(q)
+}
+
+// allRows returns all the rows of the query that have not been retrieved
+// yet, converted like in the Rows method of Query. If the rows are
+// truncated, the rows retrieved are returned along with ErrRowsTruncated.
+func allRows(q Query) ([]map[string]interface{}, error) {
+	var result []map[string]interface{}
+	for uint64(len(result)) < q.TotalRows() {
+		rows, err := q.Rows()
+		if err == ErrRowsTruncated {
+			return result, err
+		}
+
+		if err != nil {
 			return nil, err
 		}
+
+		if len(rows) == 0 {
+			break
+		}
+		result = append(result, rows...)
 	}
 
-	return newQuery(s.service, resp, s.config.ProjectID, start, maxResults), nil
+	return result, nil
 }
 
-func (s *Service) requestQuery(query string, maxResults uint64) (*bigquery.QueryResponse, error) {
+// QueryStats contains the statistics of a query.
+type QueryStats struct {
+	// TotalBytesProcessed is the number of bytes processed by the query or,
+	// in the case of a dry run, the number of bytes that would be processed.
+	TotalBytesProcessed int64
+	// CacheHit reports whether the results of the query are served from
+	// the query cache.
+	CacheHit bool
+}
+
+// DryRun validates the given SQL sentence without running it and returns the
+// statistics of the query that would be run, so the cost of the query can be
+// estimated beforehand. Dry runs are not billed and do not create any job.
+func (s *Service) DryRun(query string) (*QueryStats, error) {
+	if isEmptyQuery(query) {
+		return nil, ErrEmptyQuery
+	}
+
+	req := s.newQueryRequest(query)
+	req.DryRun = true
+
+	resp, err := s.requestQuery(context.Background(), req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &QueryStats{
+		TotalBytesProcessed: resp.TotalBytesProcessed,
+		CacheHit:            resp.CacheHit,
+	}, nil
+}
+
+// QueryWithBudget is like Query, but the query is only run if it would not
+// process more than the given number of bytes, according to a dry run of the
+// query, e.g. to prevent ad hoc queries from scanning huge tables by
+// accident. ErrBudgetExceeded is returned if the query would process more
+// bytes. As the bytes are estimated, the query may still process a few more
+// bytes when it runs, and the bytes billed may be more, as there is a minimum
+// of bytes billed per query.
+func (s *Service) QueryWithBudget(query string, maxBytes uint64) (Query, error) {
+	stats, err := s.DryRun(query)
+	if err != nil {
+		return nil, err
+	}
+
+	if uint64(stats.TotalBytesProcessed) > maxBytes {
+		return nil, ErrBudgetExceeded
+	}
+	return s.Query(query)
+}
+
+// Validate validates the given SQL sentence in BigQuery without running it,
+// the same way DryRun does, and returns the error BigQuery finds in the
+// query, if any.
+func (s *Service) Validate(query string) error {
+	_, err := s.DryRun(query)
+	return err
+}
+
+// SubmitQuery inserts a job that runs the given SQL sentence and returns its
+// ID right away, without waiting for it to finish. The results can be
+// retrieved later with WaitForResults, even by another process, as long as
+// it uses the same project.
+func (s *Service) SubmitQuery(query string) (string, error) {
+	// configuring the job, even with nothing, makes it always inserted
+	jobID, _, err := s.submitQuery(
+		context.Background(),
+		time.Now(),
+		s.newQueryRequest(query),
+		func(*bigquery.JobConfigurationQuery) {},
+		"",
+	)
+	return jobID, err
+}
+
+// WaitForResults waits for the query job with the given ID, usually submitted
+// with SubmitQuery, to finish and returns the query with its results. The
+// arguments are the same as the ones of Query.
+func (s *Service) WaitForResults(jobID string, args ...uint64) (Query, error) {
+	opts, err := s.queryArgs(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.waitForQuery(context.Background(), time.Now(), jobID, opts)
+}
+
+// GetQuery returns the query with the results of the existing query job with
+// the given ID, e.g. to retrieve the results of a job submitted by a process
+// that crashed before retrieving them. Unlike WaitForResults, it does not
+// wait for the job, so ErrJobNotDone is returned if the job is still running.
+// The arguments are the same as the ones of Query.
+func (s *Service) GetQuery(jobID string, args ...uint64) (Query, error) {
+	opts, err := s.queryArgs(args...)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	job, err := s.getJob(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Configuration != nil && job.Configuration.Query == nil {
+		return nil, fmt.Errorf("job %q is not a query job", jobID)
+	}
+
+	if job.Status.State != "DONE" {
+		return nil, ErrJobNotDone
+	}
+
+	if job.Status.ErrorResult != nil {
+		return nil, newJobError(job.Status)
+	}
+
+	return s.queryResults(ctx, jobID, opts)
+}
+
+// QueryPage returns the query with the results of the existing query job with
+// the given ID that start at the page with the given token, which is the one
+// returned by PageToken, with up to the given number of rows per page. With
+// an empty token, the results start at the beginning. This way, the results
+// can be paginated without keeping the query, e.g. across the requests of a
+// stateless web service. ErrJobNotDone is returned if the job is still
+// running.
+func (s *Service) QueryPage(jobID, pageToken string, pageSize uint64) (Query, error) {
+	opts := s.queryOptions(WithPageSize(pageSize))
+	if pageToken != "" {
+		var err error
+		opts.start, opts.pageToken, err = decodePageToken(pageToken)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx := context.Background()
+	page, err := s.getQueryResults(ctx, jobID, opts, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if !page.JobComplete {
+		return nil, ErrJobNotDone
+	}
+
+	return s.newQuery(ctx, page, opts)
+}
+
+// Ping checks that BigQuery can be reached, with the credentials of the
+// service, by validating a trivial query in its project. It returns an error
+// if the credentials are not valid or don't have access to the project, or if
+// the project or the default dataset don't exist.
+func (s *Service) Ping(ctx context.Context) error {
+	req := s.newQueryRequest("SELECT 1")
+	req.DryRun = true
+
+	_, err := s.requestQuery(ctx, req)
+	if apiErr, ok := err.(*googleapi.Error); ok {
+		switch apiErr.Code {
+		case http.StatusUnauthorized, http.StatusForbidden:
+			return fmt.Errorf("can't access project %q, check the credentials: %s", s.jobProject(), apiErr.Message)
+		case http.StatusNotFound:
+			return fmt.Errorf("project %q or dataset %q not found: %s", s.config.ProjectID, s.config.DatasetID, apiErr.Message)
+		}
+	}
+	return err
+}
+
+// query runs the given query request and returns the query with the first
+// page of its results. If configure is given, the query is inserted as a job
+// and configure is called with its configuration before inserting it.
+func (s *Service) query(
+	ctx context.Context,
+	req *bigquery.QueryRequest,
+	configure func(*bigquery.JobConfigurationQuery),
+	opts queryOptions,
+) (Query, error) {
+	if opts.maxResults > 0 {
+		req.MaxResults = int64(opts.maxResults)
+	}
+
+	if err := s.acquireSlot(ctx); err != nil {
+		return nil, err
+	}
+	defer s.releaseSlot()
+
+	var q Query
+	err := s.observe(func(submitted time.Time) error {
+		jobID, resp, err := s.submitQuery(ctx, submitted, req, configure, opts.jobID)
+		if err != nil {
+			return err
+		}
+
+		if resp == nil || !resp.JobComplete {
+			q, err = s.waitForQuery(ctx, submitted, jobID, opts)
+			return err
+		}
+
+		s.queryDone(ctx, submitted, resp)
+		page := queryResultsPage(resp)
+		if opts.start > 0 {
+			// the rows of the response are always the ones at the beginning
+			// of the resultset
+			page.Rows = nil
+		}
+
+		q, err = s.newQuery(ctx, page, opts)
+		return err
+	})
+	return q, err
+}
+
+// observe runs the given function, which runs a query, and collects its
+// metrics.
+func (s *Service) observe(run func(submitted time.Time) error) error {
+	metrics := s.config.metrics()
+	metrics.IncQuery()
+
+	submitted := time.Now()
+	if err := run(submitted); err != nil {
+		err = bytesBilledLimitError(err, s.config.MaxBytesBilled)
+		metrics.IncError(errorReason(err))
+		return err
+	}
+
+	metrics.ObserveQueryDuration(time.Since(submitted))
+	return nil
+}
+
+// submitQuery submits the given query request, at the given time, and returns
+// the ID of its job. The response of the query is returned only if it was run
+// directly instead of being inserted as a job, which happens when it has batch
+// priority or configure is given.
+func (s *Service) submitQuery(
+	ctx context.Context,
+	submitted time.Time,
+	req *bigquery.QueryRequest,
+	configure func(*bigquery.JobConfigurationQuery),
+	jobID string,
+) (string, *bigquery.QueryResponse, error) {
+	if isEmptyQuery(req.Query) {
+		return "", nil, ErrEmptyQuery
+	}
+
+	if err := validateLabels(req.Labels); err != nil {
+		return "", nil, err
+	}
+
+	if err := validateConnectionProperties(req.ConnectionProperties); err != nil {
+		return "", nil, err
+	}
+
+	inserted := s.config.Priority == PriorityBatch ||
+		configure != nil ||
+		jobID != "" ||
+		s.config.JobIDPrefix != ""
+	if inserted {
+		config := queryJobConfiguration(req, s.config.Priority)
+		if configure != nil {
+			configure(config.Query)
+		}
+
+		job, err := s.insertJob(ctx, config, jobID)
+		if err != nil {
+			return "", nil, err
+		}
+
+		jobID := job.JobReference.JobId
+		s.config.log(ctx, submitted, Event{Kind: EventQuerySubmitted, JobID: jobID})
+		return jobID, nil, nil
+	}
+
+	resp, err := s.requestQuery(ctx, req)
+	if err != nil {
+		return "", nil, err
+	}
+
+	jobID = resp.JobReference.JobId
+	s.config.log(ctx, submitted, Event{Kind: EventQuerySubmitted, JobID: jobID})
+	return jobID, resp, nil
+}
+
+// queryDone reports the end of a query which response was complete.
+func (s *Service) queryDone(ctx context.Context, submitted time.Time, resp *bigquery.QueryResponse) {
+	s.config.log(ctx, submitted, Event{
+		Kind:           EventJobDone,
+		JobID:          resp.JobReference.JobId,
+		BytesProcessed: resp.TotalBytesProcessed,
+	})
+	s.config.metrics().ObserveBytesBilled(resp.TotalBytesBilled)
+}
+
+// waitForQuery waits for the given query job, submitted at the given time, to
+// finish and returns the query with the first page of its results. Instead of
+// polling the status of the job, the results are requested right away and
+// BigQuery waits for the job to complete before responding, up to the server
+// timeout, so the first page is retrieved in the same request that detects
+// the job is done.
+func (s *Service) waitForQuery(ctx context.Context, submitted time.Time, jobID string, opts queryOptions) (Query, error) {
+	waitCtx, cancel := s.queryDeadline(ctx, submitted)
+	defer cancel()
+	defer s.trackJob(jobID)()
+
+	s.config.log(ctx, submitted, Event{Kind: EventPollStarted, JobID: jobID})
+	for {
+		timeout := s.config.waitTimeout()
+		if deadline, ok := waitCtx.Deadline(); ok {
+			if left := time.Until(deadline); left < timeout {
+				timeout = left
+			}
+		}
+
+		page, err := s.getQueryResults(waitCtx, jobID, opts, timeout)
+		if err != nil {
+			if s.queryTimedOut(ctx, waitCtx, jobID) {
+				return nil, ErrQueryTimeout
+			}
+			return nil, s.queryError(ctx, submitted, jobID, err)
+		}
+
+		state := "RUNNING"
+		if page.JobComplete {
+			state = "DONE"
+		}
+
+		s.config.log(ctx, submitted, Event{Kind: EventPoll, JobID: jobID, State: state})
+		if !page.JobComplete {
+			if s.queryTimedOut(ctx, waitCtx, jobID) {
+				return nil, ErrQueryTimeout
+			}
+
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		s.config.log(ctx, submitted, Event{
+			Kind:           EventJobDone,
+			JobID:          jobID,
+			BytesProcessed: page.TotalBytesProcessed,
+		})
+		s.observeJobBytesBilled(ctx, jobID)
+		return s.newQuery(ctx, page, opts)
+	}
+}
+
+// queryDeadline returns the context to wait for a query submitted at the
+// given time, which has the deadline of the max query duration, if any.
+func (s *Service) queryDeadline(ctx context.Context, submitted time.Time) (context.Context, context.CancelFunc) {
+	if s.config.MaxQueryDuration <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithDeadline(ctx, submitted.Add(s.config.MaxQueryDuration))
+}
+
+// queryTimedOut reports whether the given context to wait for the given query
+// job exceeded the max query duration, but not the context of the query, in
+// which case the job is cancelled.
+func (s *Service) queryTimedOut(ctx, waitCtx context.Context, jobID string) bool {
+	if waitCtx.Err() != context.DeadlineExceeded || ctx.Err() != nil {
+		return false
+	}
+
+	// the query failed anyway, so the job is cancelled on a best-effort basis
+	_, _ = s.CancelJob(jobID)
+	return true
+}
+
+// queryError returns the error of the given query job, submitted at the given
+// time, whose results could not be retrieved with the given error. BigQuery
+// responds with an error when the results of a failed job are requested, so
+// the job is retrieved to return its JobError, if it failed.
+func (s *Service) queryError(ctx context.Context, submitted time.Time, jobID string, err error) error {
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return err
+	}
+
+	job, jobErr := s.getJob(ctx, jobID)
+	if jobErr != nil || job.Status == nil || job.Status.ErrorResult == nil {
+		return err
+	}
+
+	done := Event{Kind: EventJobDone, JobID: jobID, Err: newJobError(job.Status)}
+	if job.Statistics != nil {
+		done.BytesProcessed = job.Statistics.TotalBytesProcessed
+	}
+
+	s.config.log(ctx, submitted, done)
+	s.config.metrics().ObserveBytesBilled(jobBytesBilled(job))
+	return done.Err
+}
+
+// observeJobBytesBilled collects the bytes billed for the given query job,
+// which are only in the statistics of the job, so it is only retrieved if
+// there is a metrics collector.
+func (s *Service) observeJobBytesBilled(ctx context.Context, jobID string) {
+	if s.config.Metrics == nil {
+		return
+	}
+
+	job, err := s.getJob(ctx, jobID)
+	if err != nil {
+		return
+	}
+	s.config.metrics().ObserveBytesBilled(jobBytesBilled(job))
+}
+
+// queryResults returns the query with the first page of results of the given
+// query job, which must be done.
+func (s *Service) queryResults(ctx context.Context, jobID string, opts queryOptions) (Query, error) {
+	page, err := s.getQueryResults(ctx, jobID, opts, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.newQuery(ctx, page, opts)
+}
+
+// newQuery returns the query for the given first page of results of a query
+// job that is done. In strict mode, the query fails if the job completed
+// with errors.
+func (s *Service) newQuery(ctx context.Context, page *bigquery.GetQueryResultsResponse, opts queryOptions) (Query, error) {
+	if err := checkOffset(opts.start, page.TotalRows); err != nil {
+		return nil, err
+	}
+
+	if s.config.Strict && len(page.Errors) > 0 {
+		return nil, newWarningsError(page.Errors)
+	}
+
+	q := newQuery(ctx, s.backend, page, s.jobProject(), opts.start, opts.maxResults)
+	q.retry = s.config.RetryPolicy
+	q.nameMapper = s.config.NameMapper
+	q.maxRows = opts.maxRows
+	q.lenient = opts.lenient
+	return q, nil
+}
+
+// getQueryResults returns the first page of results of the given query job,
+// waiting up to the given timeout for the job to complete.
+func (s *Service) getQueryResults(
+	ctx context.Context,
+	jobID string,
+	opts queryOptions,
+	timeout time.Duration,
+) (*bigquery.GetQueryResultsResponse, error) {
+	var page *bigquery.GetQueryResultsResponse
+	err := s.config.RetryPolicy.do(ctx, func() (err error) {
+		page, err = s.backend.GetQueryResults(
+			ctx,
+			s.jobProject(), jobID, s.config.Location,
+			opts.start, opts.maxResults,
+			opts.pageToken, timeout,
+		)
+		return err
+	})
+	return page, err
+}
+
+func (s *Service) newQueryRequest(query string) *bigquery.QueryRequest {
+	var timeoutMs int64
+	if timeout := s.config.ServerTimeout; timeout > 0 {
+		if max := s.config.MaxQueryDuration; max > 0 && max < timeout {
+			timeout = max
+		}
+		timeoutMs = int64(timeout / time.Millisecond)
+	}
+
 	req := &bigquery.QueryRequest{
-		DefaultDataset: &bigquery.DatasetReference{
+		Query:                query,
+		UseLegacySql:         googleapi.Bool(s.config.Dialect == DialectLegacy),
+		UseQueryCache:        s.config.UseCache,
+		TimeoutMs:            timeoutMs,
+		Labels:               s.config.Labels,
+		MaximumBytesBilled:   s.config.MaxBytesBilled,
+		Location:             s.config.Location,
+		ConnectionProperties: connectionProperties(s.config.ConnectionProperties),
+	}
+
+	if s.config.DatasetID != "" {
+		req.DefaultDataset = &bigquery.DatasetReference{
 			DatasetId: s.config.DatasetID,
-			ProjectId: s.config.ProjectID,
+			ProjectId: s.datasetProject(),
+		}
+	}
+
+	return req
+}
+
+// CancelJob requests the cancellation of the job with the given ID and
+// returns the state of the job after the request. Cancellation is
+// asynchronous, so the job may still be "RUNNING" right after the request,
+// and the state is "DONE" once it has been cancelled. It's safe to cancel a
+// job that has already completed.
+func (s *Service) CancelJob(jobID string) (string, error) {
+	ctx := context.Background()
+	var resp *bigquery.JobCancelResponse
+	err := s.config.RetryPolicy.do(ctx, func() (err error) {
+		resp, err = s.backend.CancelJob(ctx, s.jobProject(), jobID, s.config.Location)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return resp.Job.Status.State, nil
+}
+
+// jobProject returns the project the jobs are run in.
+func (s *Service) jobProject() string {
+	if s.config.BillingProjectID != "" {
+		return s.config.BillingProjectID
+	}
+	return s.config.ProjectID
+}
+
+// datasetProject returns the project of the default dataset.
+func (s *Service) datasetProject() string {
+	if s.datasetProjectID != "" {
+		return s.datasetProjectID
+	}
+	return s.config.ProjectID
+}
+
+// queryJobConfiguration returns the configuration of a query job equivalent to
+// the given query request, for queries that need to be inserted as jobs.
+func queryJobConfiguration(req *bigquery.QueryRequest, priority Priority) *bigquery.JobConfiguration {
+	return &bigquery.JobConfiguration{
+		DryRun: req.DryRun,
+		Labels: req.Labels,
+		Query: &bigquery.JobConfigurationQuery{
+			Query:                req.Query,
+			DefaultDataset:       req.DefaultDataset,
+			UseLegacySql:         req.UseLegacySql,
+			UseQueryCache:        req.UseQueryCache,
+			ParameterMode:        req.ParameterMode,
+			QueryParameters:      req.QueryParameters,
+			Priority:             string(priority),
+			MaximumBytesBilled:   req.MaximumBytesBilled,
+			ConnectionProperties: req.ConnectionProperties,
+			CreateSession:        req.CreateSession,
 		},
-		Query: query,
+	}
+}
+
+// requestQuery runs the given query request. If retries are enabled, the
+// request is given an unique ID so it can be safely retried without running
+// the query twice.
+func (s *Service) requestQuery(ctx context.Context, req *bigquery.QueryRequest) (*bigquery.QueryResponse, error) {
+	if s.config.RetryPolicy.enabled() && req.RequestId == "" {
+		id, err := randomID()
+		if err != nil {
+			return nil, err
+		}
+		req.RequestId = id
+	}
+
+	var resp *bigquery.QueryResponse
+	err := s.config.RetryPolicy.do(ctx, func() (err error) {
+		resp, err = s.backend.Query(ctx, s.jobProject(), req)
+		return err
+	})
+	return resp, err
+}
+
+// insertJob inserts a new job with the given configuration and ID. If there is
+// no ID, the job is given an unique ID with the prefix of the config. The
+// job always has an ID so it can be safely retried without inserting it
+// twice.
+func (s *Service) insertJob(ctx context.Context, config *bigquery.JobConfiguration, jobID string) (*bigquery.Job, error) {
+	if jobID == "" {
+		id, err := randomID()
+		if err != nil {
+			return nil, err
+		}
+		jobID = s.config.JobIDPrefix + id
 	}
 
-	if maxResults > 0 {
-		req.MaxResults = int64(maxResults)
+	job := &bigquery.Job{
+		Configuration: config,
+		JobReference: &bigquery.JobReference{
+			JobId:     jobID,
+			ProjectId: s.jobProject(),
+			Location:  s.config.Location,
+		},
 	}
 
-	return s.service.Jobs.Query(s.config.ProjectID, req).Do()
+	var inserted *bigquery.Job
+	err := s.config.RetryPolicy.do(ctx, func() (err error) {
+		inserted, err = s.backend.InsertJob(ctx, s.jobProject(), job)
+		return err
+	})
+	return inserted, err
+}
+
+func (s *Service) getJob(ctx context.Context, jobID string) (*bigquery.Job, error) {
+	var job *bigquery.Job
+	err := s.config.RetryPolicy.do(ctx, func() (err error) {
+		job, err = s.backend.GetJob(ctx, s.jobProject(), jobID, s.config.Location)
+		return err
+	})
+	return job, err
 }
 
-func (s *Service) waitForJob(jobID string) error {
+// waitForJob polls the status of the given job, submitted at the given time,
+// until it is done and returns the job. The bytes billed are only collected
+// for query jobs.
+func (s *Service) waitForJob(ctx context.Context, submitted time.Time, jobID string) (*bigquery.Job, error) {
+	defer s.trackJob(jobID)()
+
+	s.config.log(ctx, submitted, Event{Kind: EventPollStarted, JobID: jobID})
+	interval := s.config.pollInterval()
 	for {
-		job, err := s.service.Jobs.Get(s.config.ProjectID, jobID).Do()
+		job, err := s.getJob(ctx, jobID)
 		if err != nil {
-			return err
+			return nil, err
 		}
 
+		s.config.log(ctx, submitted, Event{
+			Kind:  EventPoll,
+			JobID: jobID,
+			State: job.Status.State,
+		})
 		if job.Status.State == "DONE" {
+			done := Event{Kind: EventJobDone, JobID: jobID}
+			if job.Statistics != nil {
+				done.BytesProcessed = job.Statistics.TotalBytesProcessed
+			}
+
 			if job.Status.ErrorResult != nil {
-				return errors.New(job.Status.ErrorResult.Message)
+				done.Err = newJobError(job.Status)
 			}
 
-			break
+			s.config.log(ctx, submitted, done)
+			if job.Statistics != nil && job.Statistics.Query != nil {
+				s.config.metrics().ObserveBytesBilled(jobBytesBilled(job))
+			}
+			if done.Err != nil {
+				return nil, done.Err
+			}
+			return job, nil
 		}
-		<-time.After(300 * time.Millisecond)
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+		interval = s.config.nextPollInterval(interval)
 	}
-	return nil
 }
 
-func queryArgs(args ...uint64) (uint64, uint64, error) {
-	var start, maxResults uint64
-	switch len(args) {
-	case 0:
-	case 2:
-		maxResults = args[1]
-		fallthrough
-	case 1:
-		start = args[0]
-	default:
-		return 0, 0, fmt.Errorf("too many arguments given to query: %d", len(args))
+// jobBytesBilled returns the bytes billed for the given query job.
+func jobBytesBilled(job *bigquery.Job) int64 {
+	if job.Statistics == nil || job.Statistics.Query == nil {
+		return 0
+	}
+	return job.Statistics.Query.TotalBytesBilled
+}
+
+// checkOffset returns ErrOffsetOutOfRange if the given start of a query is
+// beyond the last row of its resultset. Starting at 0 is always valid, even
+// if the resultset is empty.
+func checkOffset(start, totalRows uint64) error {
+	if start > 0 && start >= totalRows {
+		return ErrOffsetOutOfRange
 	}
-	return start, maxResults, nil
+	return nil
+}
+
+func isEmptyQuery(query string) bool {
+	return strings.TrimSpace(query) == ""
 }
@@ -1,9 +1,14 @@
 package bigq
 
 import (
+	"context"
+	"net/http"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
 )
 
 func TestServiceNew(t *testing.T) {
@@ -16,7 +21,12 @@ func TestServiceNew(t *testing.T) {
 	assert.NotNil(service)
 }
 
-const testQuery = `SELECT word
+const testQuery = "SELECT word\n" +
+	"FROM `publicdata.samples.shakespeare`\n" +
+	"ORDER BY word DESC\n" +
+	"LIMIT 20"
+
+const testLegacyQuery = `SELECT word
 FROM [publicdata:samples.shakespeare]
 ORDER BY word DESC
 LIMIT 20`
@@ -33,3 +43,746 @@ func TestServiceQuery(t *testing.T) {
 	assert.Nil(err)
 	assert.NotNil(q)
 }
+
+func TestServiceQueryContext(t *testing.T) {
+	assert := assert.New(t)
+	service, err := New(WithConfigFile(tokenFile), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	})
+	assert.Nil(err)
+
+	q, err := service.QueryContext(context.Background(), testQuery, 0, 5)
+	assert.Nil(err)
+	assert.NotNil(q)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	q, err = service.QueryContext(ctx, testQuery, 0, 5)
+	assert.NotNil(err)
+	assert.Nil(q)
+}
+
+func TestServiceQueryWithParams(t *testing.T) {
+	assert := assert.New(t)
+	service, err := New(WithConfigFile(tokenFile), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	})
+	assert.Nil(err)
+
+	q, err := service.QueryWithParams(
+		"SELECT word FROM `publicdata.samples.shakespeare` WHERE word = @word LIMIT 1",
+		map[string]interface{}{"word": "zeal"},
+	)
+	assert.Nil(err)
+	assert.NotNil(q)
+
+	rows, err := q.NextPage()
+	assert.Nil(err)
+	assert.Equal(1, len(rows))
+	assert.Equal("zeal", rows[0][0])
+}
+
+func TestServiceQueryWithArgs(t *testing.T) {
+	assert := assert.New(t)
+	service, err := New(WithConfigFile(tokenFile), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	})
+	assert.Nil(err)
+
+	q, err := service.QueryWithArgs(
+		"SELECT word FROM `publicdata.samples.shakespeare` WHERE word = ? LIMIT 1",
+		"zeal",
+	)
+	assert.Nil(err)
+	assert.NotNil(q)
+
+	rows, err := q.NextPage()
+	assert.Nil(err)
+	assert.Equal(1, len(rows))
+	assert.Equal("zeal", rows[0][0])
+
+	_, err = service.QueryWithArgs("SELECT @foo, ?", "zeal")
+	assert.Equal(errMixedParams, err)
+}
+
+func TestServiceQueryLegacy(t *testing.T) {
+	assert := assert.New(t)
+	service, err := New(WithConfigFile(tokenFile), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+		Dialect:   DialectLegacy,
+	})
+	assert.Nil(err)
+
+	q, err := service.Query(testLegacyQuery, 0, 5)
+	assert.Nil(err)
+	assert.NotNil(q)
+
+	_, err = service.QueryWithArgs("SELECT ?", "foo")
+	assert.Equal(errLegacyParams, err)
+}
+
+func TestServiceDryRun(t *testing.T) {
+	assert := assert.New(t)
+	service, err := New(WithConfigFile(tokenFile), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	})
+	assert.Nil(err)
+
+	stats, err := service.DryRun(testQuery)
+	assert.Nil(err)
+	assert.NotNil(stats)
+	assert.True(stats.TotalBytesProcessed > 0)
+}
+
+func TestServiceQueryWithBudget(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.bytesProcessed = 1024
+	service := newFakeService(backend, Config{})
+
+	_, err := service.QueryWithBudget(testQuery, 1023)
+	assert.Equal(ErrBudgetExceeded, err)
+	assert.Equal(1, backend.calls["Query"])
+	assert.True(backend.requests[0].DryRun)
+
+	q, err := service.QueryWithBudget(testQuery, 1024)
+	assert.Nil(err)
+	assert.Equal(uint64(5), q.TotalRows())
+	assert.Equal(3, backend.calls["Query"])
+	assert.False(backend.requests[2].DryRun)
+
+	_, err = service.QueryWithBudget(" ", 1024)
+	assert.Equal(ErrEmptyQuery, err)
+	assert.Equal(3, backend.calls["Query"])
+}
+
+func TestConfigPollInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	var config Config
+	assert.Equal(defaultPollInterval, config.pollInterval())
+	assert.Equal(defaultPollInterval, config.nextPollInterval(config.pollInterval()))
+
+	config = Config{
+		PollInterval:    100 * time.Millisecond,
+		MaxPollInterval: time.Second,
+	}
+	var intervals []time.Duration
+	for i, interval := 0, config.pollInterval(); i < 6; i++ {
+		intervals = append(intervals, interval)
+		interval = config.nextPollInterval(interval)
+	}
+	assert.Equal([]time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}, intervals)
+}
+
+func TestQueryJobConfiguration(t *testing.T) {
+	assert := assert.New(t)
+	service := &Service{config: Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	}}
+
+	req := service.newQueryRequest(testQuery)
+	config := queryJobConfiguration(req, PriorityBatch)
+	assert.Equal(testQuery, config.Query.Query)
+	assert.Equal("samples", config.Query.DefaultDataset.DatasetId)
+	assert.Equal("BATCH", config.Query.Priority)
+	assert.False(*config.Query.UseLegacySql)
+}
+
+func TestServiceQueryBatch(t *testing.T) {
+	assert := assert.New(t)
+	service, err := New(WithConfigFile(tokenFile), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+		Priority:  PriorityBatch,
+	})
+	assert.Nil(err)
+
+	q, err := service.Query(testQuery, 0, 5)
+	assert.Nil(err)
+	assert.NotNil(q)
+
+	rows, err := q.NextPage()
+	assert.Nil(err)
+	assert.Equal(5, len(rows))
+}
+
+func TestServiceWithCache(t *testing.T) {
+	assert := assert.New(t)
+	service := &Service{config: Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	}}
+
+	noCache := service.WithCache(false)
+	assert.Nil(service.config.UseCache)
+	assert.Nil(service.newQueryRequest(testQuery).UseQueryCache)
+	assert.False(*noCache.config.UseCache)
+	assert.False(*noCache.newQueryRequest(testQuery).UseQueryCache)
+}
+
+func TestServiceQueryCacheHit(t *testing.T) {
+	assert := assert.New(t)
+	service, err := New(WithConfigFile(tokenFile), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	})
+	assert.Nil(err)
+
+	q, err := service.WithCache(false).Query(testQuery, 0, 5)
+	assert.Nil(err)
+	assert.False(q.CacheHit())
+}
+
+func TestServiceCancelJob(t *testing.T) {
+	assert := assert.New(t)
+	service, err := New(WithConfigFile(tokenFile), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+		Priority:  PriorityBatch,
+	})
+	assert.Nil(err)
+
+	job, err := service.insertJob(context.Background(), queryJobConfiguration(
+		service.newQueryRequest(testQuery),
+		PriorityBatch,
+	), "")
+	assert.Nil(err)
+
+	state, err := service.CancelJob(job.JobReference.JobId)
+	assert.Nil(err)
+	assert.NotEqual("", state)
+
+	// cancelling a job that is already done is not an error, the wait
+	// may fail if the job was stopped before finishing
+	_, err = service.waitForJob(context.Background(), time.Now(), job.JobReference.JobId)
+	if err != nil {
+		assert.IsType(&JobError{}, err)
+	}
+
+	state, err = service.CancelJob(job.JobReference.JobId)
+	assert.Nil(err)
+	assert.Equal("DONE", state)
+}
+
+func TestServiceQueryRows(t *testing.T) {
+	assert := assert.New(t)
+	service, err := New(WithConfigFile(tokenFile), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	})
+	assert.Nil(err)
+
+	rows, err := service.QueryRows(testQuery)
+	assert.Nil(err)
+	assert.Equal(20, len(rows))
+	assert.Equal("zwaggered", rows[0]["word"])
+}
+
+func TestServiceServerTimeout(t *testing.T) {
+	assert := assert.New(t)
+	service := &Service{config: Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	}}
+	assert.Equal(int64(0), service.newQueryRequest(testQuery).TimeoutMs)
+	assert.Equal(defaultWaitTimeout, service.config.waitTimeout())
+
+	service.config.ServerTimeout = 2500 * time.Millisecond
+	assert.Equal(int64(2500), service.newQueryRequest(testQuery).TimeoutMs)
+	assert.Equal(2500*time.Millisecond, service.config.waitTimeout())
+
+	service.config.MaxQueryDuration = time.Second
+	assert.Equal(int64(1000), service.newQueryRequest(testQuery).TimeoutMs)
+}
+
+func TestServiceServerTimeoutMaxQueryDuration(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.polls = 2
+	service := newFakeService(backend, Config{
+		ServerTimeout:    time.Minute,
+		MaxQueryDuration: time.Second,
+	})
+
+	_, err := service.Query(testQuery)
+	assert.Nil(err)
+	assert.Equal(2, len(backend.timeouts))
+	for _, timeout := range backend.timeouts {
+		assert.True(timeout > 0 && timeout <= time.Second, timeout.String())
+	}
+
+	backend.timeouts = nil
+	backend.polls = 2
+	service.config.MaxQueryDuration = 0
+	_, err = service.Query(testQuery)
+	assert.Nil(err)
+	assert.Equal([]time.Duration{time.Minute, time.Minute}, backend.timeouts)
+}
+
+func TestServiceWithDataset(t *testing.T) {
+	assert := assert.New(t)
+	service := &Service{config: Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	}}
+
+	other := service.WithDataset("other")
+	assert.Equal("samples", service.newQueryRequest(testQuery).DefaultDataset.DatasetId)
+	assert.Equal("other", other.newQueryRequest(testQuery).DefaultDataset.DatasetId)
+	assert.Equal("go-bigq", other.newQueryRequest(testQuery).DefaultDataset.ProjectId)
+	assert.Nil(service.WithDataset("").newQueryRequest(testQuery).DefaultDataset)
+}
+
+func TestServiceWithProjectDataset(t *testing.T) {
+	assert := assert.New(t)
+	service := &Service{config: Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	}}
+
+	other := service.WithProjectDataset("publicdata", "samples")
+	assert.Equal("go-bigq", service.newQueryRequest(testQuery).DefaultDataset.ProjectId)
+	assert.Equal("publicdata", other.newQueryRequest(testQuery).DefaultDataset.ProjectId)
+	assert.Equal("samples", other.newQueryRequest(testQuery).DefaultDataset.DatasetId)
+	assert.Equal("go-bigq", other.config.ProjectID)
+	assert.Equal("publicdata", other.WithDataset("foo").newQueryRequest(testQuery).DefaultDataset.ProjectId)
+}
+
+func TestServiceQueryOtherProject(t *testing.T) {
+	assert := assert.New(t)
+	service, err := New(WithConfigFile(tokenFile), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	})
+	assert.Nil(err)
+
+	q, err := service.WithProjectDataset("publicdata", "samples").Query(
+		"SELECT word FROM shakespeare ORDER BY word DESC LIMIT 20", 0, 5,
+	)
+	assert.Nil(err)
+	assert.Equal(uint64(20), q.TotalRows())
+}
+
+func TestServicePing(t *testing.T) {
+	cases := []struct {
+		err     error
+		message string
+	}{
+		{nil, ""},
+		{apiError(401, "authError"), `can't access project "go-bigq", check the credentials: authError`},
+		{apiError(404, "notFound"), `project "go-bigq" or dataset "samples" not found: notFound`},
+		{apiError(400, "invalidQuery"), ""},
+	}
+
+	assert := assert.New(t)
+	for _, c := range cases {
+		backend := newFakeBackend(0)
+		backend.queryErr = c.err
+		service := newFakeService(backend, Config{})
+
+		err := service.Ping(context.Background())
+		if c.message == "" {
+			assert.Equal(c.err, err)
+		} else {
+			assert.Equal(c.message, err.Error())
+		}
+		assert.True(backend.requests[0].DryRun)
+	}
+}
+
+func TestServiceSubmitQuery(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{})
+
+	jobID, err := service.SubmitQuery(testQuery)
+	assert.Nil(err)
+	assert.Equal(backend.inserted[0].JobReference.JobId, jobID)
+	assert.Equal(testQuery, backend.inserted[0].Configuration.Query.Query)
+	assert.Equal(0, backend.calls["GetJob"])
+
+	backend.polls = 2
+	q, err := service.WaitForResults(jobID, 1, 2)
+	assert.Nil(err)
+	assert.Equal(jobID, q.JobID())
+	assert.Equal(0, backend.calls["GetJob"])
+	assert.Equal(2, backend.calls["GetQueryResults"])
+
+	rows, err := q.NextPage()
+	assert.Nil(err)
+	assert.Equal([][]interface{}{{"1"}, {"2"}}, rows)
+}
+
+func TestServiceGetQuery(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.polls = 2
+	service := newFakeService(backend, Config{})
+
+	_, err := service.GetQuery("job", 0, 2)
+	assert.Equal(ErrJobNotDone, err)
+
+	q, err := service.GetQuery("job", 0, 2)
+	assert.Nil(err)
+	assert.Equal("job", q.JobID())
+	assert.Equal(uint64(5), q.TotalRows())
+
+	backend.jobError = &bigquery.ErrorProto{Reason: "invalidQuery"}
+	_, err = service.GetQuery("job")
+	assert.IsType(&JobError{}, err)
+}
+
+func TestServiceEmptyQuery(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{})
+
+	_, err := service.Query("")
+	assert.Equal(ErrEmptyQuery, err)
+
+	_, err = service.Query(" \n\t ")
+	assert.Equal(ErrEmptyQuery, err)
+
+	_, err = service.Execute(" ")
+	assert.Equal(ErrEmptyQuery, err)
+
+	assert.Equal(ErrEmptyQuery, service.Validate(" "))
+	assert.Equal(0, len(backend.requests))
+	assert.Equal(0, len(backend.inserted))
+
+	assert.Nil(service.Validate(testQuery))
+	assert.True(backend.requests[0].DryRun)
+}
+
+func TestServiceDefaultMaxResults(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{DefaultMaxResults: 2})
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+	assert.Equal(int64(2), backend.requests[0].MaxResults)
+
+	rows, err := q.NextPage()
+	assert.Nil(err)
+	assert.Equal(2, len(rows))
+
+	rows, err = q.NextPage()
+	assert.Nil(err)
+	assert.Equal(2, len(rows))
+
+	_, err = service.Query(testQuery, 1, 3)
+	assert.Nil(err)
+	assert.Equal(int64(3), backend.requests[1].MaxResults)
+}
+
+func TestServiceLocation(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.polls = 1
+	service := newFakeService(backend, Config{Location: "EU"})
+
+	q, err := service.QueryOpts(testQuery, WithPageSize(2))
+	assert.Nil(err)
+	assert.Equal("EU", backend.requests[0].Location)
+	assert.Equal("EU", q.Location())
+
+	for i := 0; i < 2; i++ {
+		_, err = q.NextPage()
+		assert.Nil(err)
+	}
+
+	_, err = service.SubmitQuery(testQuery)
+	assert.Nil(err)
+	assert.Equal("EU", backend.inserted[0].JobReference.Location)
+
+	_, err = service.CancelJob("job")
+	assert.Nil(err)
+	assert.Equal([]string{"EU", "EU", "EU"}, backend.locations)
+}
+
+func TestServiceMaxQueryDuration(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.polls = 1 << 30
+	service := newFakeService(backend, Config{MaxQueryDuration: 10 * time.Millisecond})
+
+	_, err := service.Query(testQuery)
+	assert.Equal(ErrQueryTimeout, err)
+	assert.Equal(1, backend.calls["CancelJob"])
+
+	_, err = service.Execute("DELETE FROM words WHERE true")
+	assert.Equal(ErrQueryTimeout, err)
+	assert.Equal(2, backend.calls["CancelJob"])
+
+	backend.polls = 2
+	_, err = service.Query(testQuery)
+	assert.Nil(err)
+	assert.Equal(2, backend.calls["CancelJob"])
+}
+
+func TestServiceWarnings(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.warnings = []*bigquery.ErrorProto{
+		{Reason: "invalid", Message: "Some files could not be read"},
+	}
+	service := newFakeService(backend, Config{})
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+	assert.Equal([]string{"Some files could not be read"}, q.Warnings())
+
+	backend.polls = 2
+	service.config.Strict = true
+	_, err = service.Query(testQuery)
+	assert.IsType(&JobError{}, err)
+	assert.Equal("invalid", err.(*JobError).Reason)
+	assert.Equal(backend.warnings, err.(*JobError).Errors)
+
+	backend.warnings = nil
+	q, err = service.Query(testQuery)
+	assert.Nil(err)
+	assert.Equal(0, len(q.Warnings()))
+}
+
+func TestServiceMaxConcurrentQueries(t *testing.T) {
+	assert := assert.New(t)
+	service := newFakeService(newFakeBackend(5), Config{MaxConcurrentQueries: 1})
+	derived := service.WithLabels(map[string]string{"team": "data"})
+
+	assert.Nil(service.acquireSlot(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := derived.QueryContext(ctx, testQuery)
+	assert.Equal(context.DeadlineExceeded, err)
+
+	service.releaseSlot()
+	_, err = derived.Query(testQuery)
+	assert.Nil(err)
+	assert.Equal(0, len(service.slots))
+
+	unlimited := newFakeService(newFakeBackend(5), Config{})
+	assert.Nil(unlimited.slots)
+	_, err = unlimited.Query(testQuery)
+	assert.Nil(err)
+}
+
+func TestServiceMaxRows(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{DefaultMaxResults: 2, MaxRows: 3})
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+
+	rows, err := q.NextPage()
+	assert.Nil(err)
+	assert.Equal([][]interface{}{{"0"}, {"1"}}, rows)
+
+	rows, err = q.NextPage()
+	assert.Nil(err)
+	assert.Equal([][]interface{}{{"2"}}, rows)
+
+	_, err = q.NextPage()
+	assert.Equal(ErrRowsTruncated, err)
+	assert.Equal(1, backend.calls["GetQueryResults"])
+
+	result, err := service.QueryRows(testQuery)
+	assert.Equal(ErrRowsTruncated, err)
+	assert.Equal(3, len(result))
+
+	q, err = service.QueryOpts(testQuery, WithOffset(3), WithMaxRows(2))
+	assert.Nil(err)
+
+	var n int
+	err = q.ForEach(func(map[string]interface{}) error {
+		n++
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal(2, n)
+
+	q, err = service.QueryOpts(testQuery, WithMaxRows(0))
+	assert.Nil(err)
+
+	it := q.All()
+	for _, ok := it.Next(); ok; _, ok = it.Next() {
+		n++
+	}
+	assert.Nil(it.Err())
+	assert.Equal(7, n)
+}
+
+func TestServiceLenientConversion(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.rows[1].F[0].V = "foo"
+	backend.rows[3].F[0].V = "bar"
+	service := newFakeService(backend, Config{DefaultMaxResults: 2})
+
+	q, err := service.Query(testQuery)
+	assert.Nil(err)
+	assert.NotNil(q.ForEach(func(map[string]interface{}) error {
+		return nil
+	}))
+
+	q, err = service.QueryOpts(testQuery, WithLenientConversion())
+	assert.Nil(err)
+
+	var values []interface{}
+	err = q.ForEach(func(row map[string]interface{}) error {
+		values = append(values, row["n"])
+		return nil
+	})
+	assert.Nil(err)
+	assert.Equal([]interface{}{int64(0), nil, int64(2), nil, int64(4)}, values)
+
+	errs := q.Errors()
+	assert.Len(errs, 2)
+	assert.Equal(uint64(1), errs[0].Row)
+	assert.Equal("n", errs[0].Column)
+	assert.NotNil(errs[0].Err)
+	assert.Equal(uint64(3), errs[1].Row)
+
+	q, err = service.QueryOpts(testQuery, WithLenientConversion(), WithOffset(1))
+	assert.Nil(err)
+
+	var ns []int64
+	it := q.Iter()
+	for it.Next(nil) {
+		var n int64 = -1
+		assert.Nil(it.Scan(&n))
+		ns = append(ns, n)
+	}
+	assert.Nil(it.Err())
+	assert.Equal([]int64{0, 2, 0, 4}, ns)
+	assert.Len(q.Errors(), 2)
+	assert.Equal(uint64(3), q.Errors()[1].Row)
+}
+
+func TestServiceCancelAll(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.polls = 3
+
+	var service *Service
+	var cancelErrs []error
+	service = newFakeService(backend, Config{
+		Location: "EU",
+		Logger: LoggerFunc(func(ctx context.Context, e Event) {
+			if e.Kind == EventPoll && e.State == "RUNNING" {
+				cancelErrs = append(cancelErrs, service.WithDataset("other").CancelAll(ctx))
+			}
+		}),
+	})
+
+	assert.Nil(service.LoadFromGCS("words", "gs://bucket/words.csv"))
+	assert.Equal([]error{nil, nil}, cancelErrs)
+	assert.Equal(2, backend.calls["CancelJob"])
+	assert.Equal("EU", backend.locations[len(backend.locations)-1])
+
+	// the job is not waited for anymore once it is done
+	assert.Nil(service.CancelAll(context.Background()))
+	assert.Equal(2, backend.calls["CancelJob"])
+}
+
+func TestServiceClose(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{MaxConcurrentQueries: 1})
+	derived := service.WithDataset("other")
+
+	assert.Nil(service.acquireSlot(context.Background()))
+
+	errs := make(chan error)
+	go func() {
+		_, err := derived.Query(testQuery)
+		errs <- err
+	}()
+
+	assert.Nil(service.Close())
+	assert.Equal(ErrServiceClosed, <-errs)
+
+	_, err := service.Query(testQuery)
+	assert.Equal(ErrServiceClosed, err)
+	_, err = service.Execute(testQuery)
+	assert.Equal(ErrServiceClosed, err)
+	assert.Equal(0, backend.calls["Query"])
+
+	assert.Nil(derived.Close())
+}
+
+func TestServiceCloseIdleConnections(t *testing.T) {
+	assert := assert.New(t)
+	transport := new(closingTransport)
+	service, err := New(WithHTTPClient(&http.Client{Transport: transport}), Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	})
+	assert.Nil(err)
+
+	assert.Nil(service.Close())
+	assert.Nil(service.Close())
+	assert.Equal(1, transport.closed)
+}
+
+// closingTransport is a transport that counts the times its idle connections
+// are closed.
+type closingTransport struct {
+	http.RoundTripper
+	closed int
+}
+
+func (t *closingTransport) CloseIdleConnections() {
+	t.closed++
+}
+
+func TestServiceJobID(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{})
+
+	q, err := service.QueryOpts(testQuery, WithJobID("req-1234"))
+	assert.Nil(err)
+	assert.Equal("req-1234", q.JobID())
+	assert.Equal(0, backend.calls["Query"])
+
+	service.config.JobIDPrefix = "req_1234_"
+	q, err = service.Query(testQuery)
+	assert.Nil(err)
+	assert.True(strings.HasPrefix(q.JobID(), "req_1234_"))
+	assert.NotEqual("req_1234_", q.JobID())
+
+	q2, err := service.Query(testQuery)
+	assert.Nil(err)
+	assert.NotEqual(q.JobID(), q2.JobID())
+	assert.Equal(0, backend.calls["Query"])
+	assert.Equal(3, backend.calls["InsertJob"])
+}
+
+func TestServiceRaw(t *testing.T) {
+	assert := assert.New(t)
+	raw := new(bigquery.Service)
+	service := newWithBackend(Config{}, &serviceBackend{service: raw})
+	assert.Equal(raw, service.Raw())
+	assert.Equal(raw, service.WithDataset("other").Raw())
+
+	assert.Nil(newFakeService(newFakeBackend(0), Config{}).Raw())
+}
@@ -0,0 +1,81 @@
+package bigq
+
+import (
+	"context"
+	"errors"
+)
+
+const sessionIDProperty = "session_id"
+
+var (
+	errNoSession     = errors.New("the session was not created by BigQuery")
+	errLegacySession = errors.New("sessions can not be used with the legacy SQL dialect")
+)
+
+// Session is a BigQuery session, in which the queries share their state, such
+// as the temporary tables and the variables created by the previous queries,
+// e.g. to run the statements of a multi-statement script one by one. The
+// queries of a session are run with the config of the service that created
+// it, and they can't be run with the legacy SQL dialect.
+type Session struct {
+	s  *Service
+	id string
+}
+
+// NewSession creates a new session by running a trivial query in it. The
+// session ends once it is closed or, otherwise, once it has been inactive for
+// 24 hours. As the queries of a session can't be run with the legacy SQL
+// dialect, it fails if the dialect of the service is DialectLegacy.
+func (s *Service) NewSession() (*Session, error) {
+	if s.config.Dialect == DialectLegacy {
+		return nil, errLegacySession
+	}
+
+	ctx := context.Background()
+	req := s.newQueryRequest("SELECT 1")
+	req.CreateSession = true
+
+	q, err := s.query(ctx, req, nil, s.queryOptions())
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.getJob(ctx, q.JobID())
+	if err != nil {
+		return nil, err
+	}
+
+	if job.Statistics == nil || job.Statistics.SessionInfo == nil || job.Statistics.SessionInfo.SessionId == "" {
+		return nil, errNoSession
+	}
+
+	id := job.Statistics.SessionInfo.SessionId
+	svc := s.WithConnectionProperties(map[string]string{sessionIDProperty: id})
+	return &Session{s: svc, id: id}, nil
+}
+
+// ID returns the ID of the session.
+func (s *Session) ID() string {
+	return s.id
+}
+
+// Query is like the QueryOpts method of Service, but the query is run in the
+// session, so it can use the state created by the previous queries of the
+// session.
+func (s *Session) Query(query string, opts ...QueryOption) (Query, error) {
+	return s.s.QueryOpts(query, opts...)
+}
+
+// Execute is like the Execute method of Service, but the statement is run in
+// the session, e.g. to create a temporary table used by the next queries of
+// the session.
+func (s *Session) Execute(statement string) (*ExecResult, error) {
+	return s.s.Execute(statement)
+}
+
+// Close ends the session, so its state is discarded and no more queries can
+// be run in it.
+func (s *Session) Close() error {
+	_, err := s.s.Execute("CALL BQ.ABORT_SESSION()")
+	return err
+}
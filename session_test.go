@@ -0,0 +1,55 @@
+package bigq
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestServiceNewSession(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.sessionID = "session"
+	service := newFakeService(backend, Config{})
+
+	session, err := service.NewSession()
+	assert.Nil(err)
+	assert.Equal("session", session.ID())
+	assert.True(backend.requests[0].CreateSession)
+	assert.Len(backend.requests[0].ConnectionProperties, 0)
+
+	properties := []*bigquery.ConnectionProperty{{Key: "session_id", Value: "session"}}
+	_, err = session.Execute("CREATE TEMP TABLE words AS SELECT 'zeal' AS word")
+	assert.Nil(err)
+	assert.False(backend.requests[1].CreateSession)
+	assert.Equal(properties, backend.requests[1].ConnectionProperties)
+
+	q, err := session.Query("SELECT word FROM words", WithJobID("words"))
+	assert.Nil(err)
+	assert.Equal(uint64(5), q.TotalRows())
+	assert.Equal(properties, backend.inserted[0].Configuration.Query.ConnectionProperties)
+
+	_, err = service.Query(testQuery)
+	assert.Nil(err)
+	assert.Len(backend.requests[2].ConnectionProperties, 0)
+
+	assert.Nil(session.Close())
+	assert.Equal("CALL BQ.ABORT_SESSION()", backend.requests[3].Query)
+	assert.Equal(properties, backend.requests[3].ConnectionProperties)
+
+	backend.sessionID = ""
+	_, err = service.NewSession()
+	assert.Equal(errNoSession, err)
+}
+
+func TestServiceNewSessionLegacy(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.sessionID = "session"
+	service := newFakeService(backend, Config{Dialect: DialectLegacy})
+
+	_, err := service.NewSession()
+	assert.Equal(errLegacySession, err)
+	assert.Equal(0, backend.calls["Query"])
+}
@@ -0,0 +1,65 @@
+package bigq
+
+import "context"
+
+// Row is a row of a query resultset, with the values of its columns by name
+// converted to Go types, the same way they are converted by Rows.
+type Row map[string]interface{}
+
+// Stream retrieves all the rows of the query resultset in the background,
+// fetching the pages as they are needed, and sends them one by one on the
+// returned channel, which is closed once all the rows are sent. If there is
+// an error, it is sent on the error channel and no more rows are sent. The
+// stream stops when the given context is cancelled, in which case the error
+// of the context is sent. The error channel is always closed after the rows
+// channel. Using this method sets the query in "stream" mode, that is, the
+// NextPage method can't be used after using Stream.
+func (q *query) Stream(ctx context.Context) (<-chan Row, <-chan error) {
+	q.mode = streamMode
+	rows := make(chan Row)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(rows)
+
+		if err := q.stream(ctx, rows); err != nil {
+			errs <- err
+		}
+	}()
+
+	return rows, errs
+}
+
+func (q *query) stream(ctx context.Context, rows chan<- Row) error {
+	for q.initialRows != nil || q.sentRows < q.totalRows {
+		page, err := q.fetchPage(ctx)
+		if err != nil {
+			return err
+		}
+
+		if len(page) == 0 {
+			return nil
+		}
+
+		for i, r := range page {
+			row, err := rowMap(q.schema, r, q.cellErrors(i))
+			if err != nil {
+				return err
+			}
+
+			// the context is checked first so the stream stops right away
+			// even if the rows are still being received
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			select {
+			case rows <- row:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	return nil
+}
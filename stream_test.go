@@ -0,0 +1,46 @@
+package bigq
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryStream(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{})
+
+	q, err := service.QueryOpts(testQuery, WithPageSize(2))
+	assert.Nil(err)
+
+	rows, errs := q.Stream(context.Background())
+	var result []Row
+	for row := range rows {
+		result = append(result, row)
+	}
+	assert.Nil(<-errs)
+	assert.Equal([]Row{{"n": int64(0)}, {"n": int64(1)}, {"n": int64(2)}, {"n": int64(3)}, {"n": int64(4)}}, result)
+	assert.Equal(2, backend.calls["GetQueryResults"])
+
+	_, err = q.NextPage()
+	assert.Equal(errStreaming, err)
+}
+
+func TestQueryStreamCancel(t *testing.T) {
+	assert := assert.New(t)
+	service := newFakeService(newFakeBackend(5), Config{})
+
+	q, err := service.QueryOpts(testQuery, WithPageSize(2))
+	assert.Nil(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	rows, errs := q.Stream(ctx)
+	assert.Equal(Row{"n": int64(0)}, <-rows)
+	cancel()
+
+	for range rows {
+	}
+	assert.Equal(context.Canceled, <-errs)
+}
@@ -0,0 +1,319 @@
+package bigq
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/api/bigquery/v2"
+	"google.golang.org/api/googleapi"
+)
+
+// ErrTableNotFound is returned when the table requested does not exist.
+var ErrTableNotFound = errors.New("the table does not exist")
+
+// TableInfo is the metadata of a table.
+type TableInfo struct {
+	// Schema are the fields of the schema of the table.
+	Schema []*bigquery.TableFieldSchema
+	// NumRows is the number of rows of the table, excluding the rows in its
+	// streaming buffer.
+	NumRows uint64
+	// SizeBytes is the size of the table in bytes, excluding the rows in its
+	// streaming buffer.
+	SizeBytes int64
+	// LastModified is the time the table was last modified, or the zero time
+	// if it is not known.
+	LastModified time.Time
+}
+
+// TableOption is an option of the destination table of a query.
+type TableOption interface {
+	applyTable(*bigquery.JobConfigurationQuery)
+}
+
+// WriteDisposition specifies what happens when the destination table of a
+// query already exists.
+type WriteDisposition string
+
+const (
+	// WriteEmpty writes the results only if the table is empty, otherwise
+	// the query fails. It is the default.
+	WriteEmpty WriteDisposition = "WRITE_EMPTY"
+	// WriteTruncate replaces the data of the table with the results.
+	WriteTruncate WriteDisposition = "WRITE_TRUNCATE"
+	// WriteAppend appends the results to the data of the table.
+	WriteAppend WriteDisposition = "WRITE_APPEND"
+)
+
+func (d WriteDisposition) applyTable(config *bigquery.JobConfigurationQuery) {
+	config.WriteDisposition = string(d)
+}
+
+// CreateDisposition specifies whether the destination table of a query is
+// created if it does not exist.
+type CreateDisposition string
+
+const (
+	// CreateIfNeeded creates the table if it does not exist. It is the
+	// default.
+	CreateIfNeeded CreateDisposition = "CREATE_IF_NEEDED"
+	// CreateNever does not create the table, so the query fails if it does
+	// not exist.
+	CreateNever CreateDisposition = "CREATE_NEVER"
+)
+
+func (d CreateDisposition) applyTable(config *bigquery.JobConfigurationQuery) {
+	config.CreateDisposition = string(d)
+}
+
+// FlattenResults is a TableOption that specifies whether the nested and
+// repeated fields of the results of a legacy SQL query are flattened, which
+// they are by default. Not flattening the results, e.g. to preserve the
+// structure of ARRAY<STRUCT> outputs, requires AllowLargeResults, so it is
+// allowed as well. Standard SQL queries never flatten their results.
+type FlattenResults bool
+
+func (f FlattenResults) applyTable(config *bigquery.JobConfigurationQuery) {
+	flatten := bool(f)
+	config.FlattenResults = &flatten
+	if !flatten {
+		config.AllowLargeResults = true
+	}
+}
+
+// AllowLargeResults is a TableOption that specifies whether a legacy SQL query
+// can write results larger than the maximum response size to the table. It
+// requires a destination table, which is why it can only be given to
+// QueryToTable. Standard SQL queries always allow large results written to a
+// table.
+type AllowLargeResults bool
+
+func (a AllowLargeResults) applyTable(config *bigquery.JobConfigurationQuery) {
+	config.AllowLargeResults = bool(a)
+}
+
+// errLargeResultsWithoutTable is returned when large results are allowed
+// without giving the destination table they must be written to.
+var errLargeResultsWithoutTable = errors.New("large results can only be allowed with a destination table")
+
+// DestinationExpiration is a TableOption that makes the destination table of
+// a query expire after the given time since the query is done, so it is
+// deleted automatically, e.g. to not keep scratch tables around. The
+// expiration is set on a best-effort basis, updating the table once the job
+// of the query is done.
+type DestinationExpiration time.Duration
+
+func (DestinationExpiration) applyTable(*bigquery.JobConfigurationQuery) {}
+
+// ExpirationError is returned, along with the query, when the query to a
+// table succeeded but the expiration of the table could not be set.
+type ExpirationError struct {
+	// Table is the table whose expiration could not be set.
+	Table *bigquery.TableReference
+	// Err is the error of the update of the table.
+	Err error
+}
+
+// Error returns the message of the error.
+func (e *ExpirationError) Error() string {
+	return fmt.Sprintf("the expiration of the table %s:%s.%s could not be set: %s",
+		e.Table.ProjectId, e.Table.DatasetId, e.Table.TableId, e.Err)
+}
+
+// QueryToTable is like Query but the results are written to the given table
+// instead of a temporary one, so they are kept once the query is done. The
+// table can be given as "table", which belongs to the default dataset,
+// "dataset.table" or "project.dataset.table". The write and create
+// dispositions of the table can be given as options, e.g. WriteTruncate to
+// replace the data of the table with the results. The query is inserted as
+// a job, so the first page of results is always fetched once the job is
+// done. If the query succeeds but the DestinationExpiration given can't be
+// set, the query is returned along with an ExpirationError.
+func (s *Service) QueryToTable(query, destTable string, opts ...TableOption) (Query, error) {
+	if destTable == "" {
+		for _, opt := range opts {
+			if allow, ok := opt.(AllowLargeResults); ok && bool(allow) {
+				return nil, errLargeResultsWithoutTable
+			}
+		}
+	}
+
+	table, err := s.tableReference(destTable)
+	if err != nil {
+		return nil, err
+	}
+
+	var expiration time.Duration
+	configure := func(config *bigquery.JobConfigurationQuery) {
+		config.DestinationTable = table
+		for _, opt := range opts {
+			if d, ok := opt.(DestinationExpiration); ok {
+				expiration = time.Duration(d)
+			}
+			opt.applyTable(config)
+		}
+	}
+
+	ctx := context.Background()
+	q, err := s.query(ctx, s.newQueryRequest(query), configure, s.queryOptions())
+	if err != nil || expiration <= 0 {
+		return q, err
+	}
+
+	expires := time.Now().Add(expiration)
+	err = s.config.RetryPolicy.do(ctx, func() error {
+		_, err := s.backend.PatchTable(ctx, table.ProjectId, table.DatasetId, table.TableId, &bigquery.Table{
+			ExpirationTime: expires.UnixNano() / int64(time.Millisecond),
+		})
+		return err
+	})
+	if err != nil {
+		return q, &ExpirationError{Table: table, Err: err}
+	}
+	return q, nil
+}
+
+// TableMetadata returns the metadata of the given table, which is given the
+// same way as the destination table of QueryToTable. ErrTableNotFound is
+// returned if the table does not exist, so it can be checked without running
+// a query. If the table has a partition decorator, such as
+// "results$20240101", the metadata is the one of the partitioned table.
+func (s *Service) TableMetadata(tableID string) (*TableInfo, error) {
+	ref, err := s.tableReference(tableID)
+	if err != nil {
+		return nil, err
+	}
+
+	name, _ := splitPartition(ref.TableId)
+	ctx := context.Background()
+	var table *bigquery.Table
+	err = s.config.RetryPolicy.do(ctx, func() (err error) {
+		table, err = s.backend.GetTable(ctx, ref.ProjectId, ref.DatasetId, name)
+		return err
+	})
+	if err != nil {
+		if e, ok := err.(*googleapi.Error); ok && e.Code == http.StatusNotFound {
+			return nil, ErrTableNotFound
+		}
+		return nil, err
+	}
+
+	info := &TableInfo{
+		NumRows:      table.NumRows,
+		SizeBytes:    table.NumBytes,
+		LastModified: msTime(int64(table.LastModifiedTime)),
+	}
+	if table.Schema != nil {
+		info.Schema = table.Schema.Fields
+	}
+	return info, nil
+}
+
+// ListTables returns the IDs of all the tables of the default dataset.
+func (s *Service) ListTables() ([]string, error) {
+	ctx := context.Background()
+	var ids []string
+	var pageToken string
+	for {
+		var list *bigquery.TableList
+		err := s.config.RetryPolicy.do(ctx, func() (err error) {
+			list, err = s.backend.ListTables(ctx, s.datasetProject(), s.config.DatasetID, pageToken)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, table := range list.Tables {
+			ids = append(ids, table.TableReference.TableId)
+		}
+
+		if list.NextPageToken == "" {
+			return ids, nil
+		}
+		pageToken = list.NextPageToken
+	}
+}
+
+// tableReference returns the reference to the given table, which can be
+// given as "table", "dataset.table", "project.dataset.table" or
+// "project:dataset.table". The missing parts are the ones of the default
+// dataset. The table can have a partition decorator, such as
+// "table$20240101", which is kept in the ID of the table.
+func (s *Service) tableReference(name string) (*bigquery.TableReference, error) {
+	project, table := "", name
+	if i := strings.IndexByte(table, ':'); i >= 0 {
+		project, table = table[:i], table[i+1:]
+	}
+
+	ref := &bigquery.TableReference{
+		ProjectId: s.datasetProject(),
+		DatasetId: s.config.DatasetID,
+	}
+
+	parts := strings.Split(table, ".")
+	switch {
+	case len(parts) == 1 && project == "":
+		ref.TableId = parts[0]
+	case len(parts) == 2:
+		ref.DatasetId, ref.TableId = parts[0], parts[1]
+		if project != "" {
+			ref.ProjectId = project
+		}
+	case len(parts) == 3 && project == "":
+		ref.ProjectId, ref.DatasetId, ref.TableId = parts[0], parts[1], parts[2]
+	default:
+		return nil, fmt.Errorf("invalid table %q", name)
+	}
+
+	if ref.ProjectId == "" || ref.DatasetId == "" || ref.TableId == "" {
+		return nil, fmt.Errorf("invalid table %q: project, dataset and table can't be empty", name)
+	}
+
+	if i := strings.IndexByte(ref.TableId, '$'); i >= 0 && (i == 0 || !validPartition(ref.TableId[i+1:])) {
+		return nil, fmt.Errorf("invalid table %q: invalid partition decorator", name)
+	}
+	return ref, nil
+}
+
+// partitionFormats are the formats of the partition decorators of the time
+// partitioned tables, by their length, for the hourly, daily, monthly and
+// yearly partitions.
+var partitionFormats = map[int]string{
+	10: "2006010215",
+	8:  "20060102",
+	6:  "200601",
+	4:  "2006",
+}
+
+// splitPartition returns the table and the partition of the given table ID
+// with a partition decorator, such as "table$20240101". If there is no
+// decorator, the partition is empty.
+func splitPartition(tableID string) (table, partition string) {
+	if i := strings.IndexByte(tableID, '$'); i >= 0 {
+		return tableID[:i], tableID[i+1:]
+	}
+	return tableID, ""
+}
+
+// validPartition reports whether the given partition of a decorator is a
+// valid partition of a time partitioned table, that is, an hour, day, month
+// or year, or the partitions of the rows with NULL or without a value in the
+// partitioning column.
+func validPartition(partition string) bool {
+	switch partition {
+	case "__NULL__", "__UNPARTITIONED__":
+		return true
+	}
+
+	format, ok := partitionFormats[len(partition)]
+	if !ok {
+		return false
+	}
+	_, err := time.Parse(format, partition)
+	return err == nil
+}
@@ -0,0 +1,216 @@
+package bigq
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+func TestServiceTableReference(t *testing.T) {
+	cases := []struct {
+		table string
+		ref   *bigquery.TableReference
+	}{
+		{"results", &bigquery.TableReference{ProjectId: "go-bigq", DatasetId: "samples", TableId: "results"}},
+		{"other.results", &bigquery.TableReference{ProjectId: "go-bigq", DatasetId: "other", TableId: "results"}},
+		{"proj.other.results", &bigquery.TableReference{ProjectId: "proj", DatasetId: "other", TableId: "results"}},
+		{"proj:other.results", &bigquery.TableReference{ProjectId: "proj", DatasetId: "other", TableId: "results"}},
+		{"", nil},
+		{"proj:results", nil},
+		{"a.b.c.d", nil},
+		{"other.", nil},
+		{"results$20240101", &bigquery.TableReference{ProjectId: "go-bigq", DatasetId: "samples", TableId: "results$20240101"}},
+		{"other.results$2024010123", &bigquery.TableReference{ProjectId: "go-bigq", DatasetId: "other", TableId: "results$2024010123"}},
+		{"results$202401", &bigquery.TableReference{ProjectId: "go-bigq", DatasetId: "samples", TableId: "results$202401"}},
+		{"results$__NULL__", &bigquery.TableReference{ProjectId: "go-bigq", DatasetId: "samples", TableId: "results$__NULL__"}},
+		{"results$20241301", nil},
+		{"results$2024-01-01", nil},
+		{"results$", nil},
+		{"$20240101", nil},
+	}
+
+	assert := assert.New(t)
+	service := &Service{config: Config{
+		ProjectID: "go-bigq",
+		DatasetID: "samples",
+	}}
+	for _, c := range cases {
+		ref, err := service.tableReference(c.table)
+		assert.Equal(c.ref, ref, c.table)
+		assert.Equal(c.ref == nil, err != nil, c.table)
+	}
+
+	_, err := service.WithDataset("").tableReference("results")
+	assert.NotNil(err)
+}
+
+func TestServiceQueryToTable(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{})
+
+	q, err := service.QueryToTable(testQuery, "results", WriteTruncate, CreateNever)
+	assert.Nil(err)
+	assert.Equal(uint64(5), q.TotalRows())
+	assert.Equal(0, backend.calls["Query"])
+	assert.Equal(1, backend.calls["InsertJob"])
+
+	config := backend.inserted[0].Configuration.Query
+	assert.Equal(testQuery, config.Query)
+	assert.Equal("results", config.DestinationTable.TableId)
+	assert.Equal("samples", config.DestinationTable.DatasetId)
+	assert.Equal("WRITE_TRUNCATE", config.WriteDisposition)
+	assert.Equal("CREATE_NEVER", config.CreateDisposition)
+
+	_, err = service.QueryToTable(testQuery, "a.b.c.d")
+	assert.NotNil(err)
+	assert.Equal(1, backend.calls["InsertJob"])
+}
+
+func TestServiceQueryToTableFlattenResults(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.schema = &bigquery.TableSchema{Fields: []*bigquery.TableFieldSchema{
+		{Name: "orders", Type: "RECORD", Mode: "REPEATED", Fields: []*bigquery.TableFieldSchema{
+			{Name: "id", Type: "INTEGER"},
+			{Name: "items", Type: "STRING", Mode: "REPEATED"},
+		}},
+	}}
+	backend.rows = []*bigquery.TableRow{{F: []*bigquery.TableCell{
+		{V: []interface{}{
+			map[string]interface{}{"v": map[string]interface{}{"f": []interface{}{
+				map[string]interface{}{"v": "1"},
+				map[string]interface{}{"v": []interface{}{
+					map[string]interface{}{"v": "a"},
+					map[string]interface{}{"v": "b"},
+				}},
+			}}},
+		}},
+	}}}
+	service := newFakeService(backend, Config{Dialect: DialectLegacy})
+
+	q, err := service.QueryToTable(testQuery, "results", FlattenResults(false))
+	assert.Nil(err)
+
+	config := backend.inserted[0].Configuration.Query
+	assert.NotNil(config.FlattenResults)
+	assert.False(*config.FlattenResults)
+	assert.True(config.AllowLargeResults)
+
+	rows, err := q.Rows()
+	assert.Nil(err)
+	assert.Equal([]map[string]interface{}{{
+		"orders": []map[string]interface{}{
+			{"id": int64(1), "items": []interface{}{"a", "b"}},
+		},
+	}}, rows)
+
+	_, err = service.QueryToTable(testQuery, "results", FlattenResults(true))
+	assert.Nil(err)
+
+	config = backend.inserted[1].Configuration.Query
+	assert.True(*config.FlattenResults)
+	assert.False(config.AllowLargeResults)
+}
+
+func TestServiceQueryToTableAllowLargeResults(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	service := newFakeService(backend, Config{Dialect: DialectLegacy})
+
+	_, err := service.QueryToTable(testQuery, "results", AllowLargeResults(true))
+	assert.Nil(err)
+	assert.True(backend.inserted[0].Configuration.Query.AllowLargeResults)
+
+	_, err = service.QueryToTable(testQuery, "", AllowLargeResults(true))
+	assert.Equal(errLargeResultsWithoutTable, err)
+	assert.Equal(1, backend.calls["InsertJob"])
+}
+
+func TestServiceTableMetadata(t *testing.T) {
+	assert := assert.New(t)
+	fields := []*bigquery.TableFieldSchema{{Name: "word", Type: "STRING"}}
+	backend := newFakeBackend(0)
+	backend.tables = map[string]*bigquery.Table{
+		"samples.results": {
+			Schema:           &bigquery.TableSchema{Fields: fields},
+			NumRows:          20,
+			NumBytes:         1024,
+			LastModifiedTime: 1500000000000,
+		},
+		"samples.empty": {},
+	}
+	service := newFakeService(backend, Config{})
+
+	info, err := service.TableMetadata("results")
+	assert.Nil(err)
+	assert.Equal(&TableInfo{
+		Schema:       fields,
+		NumRows:      20,
+		SizeBytes:    1024,
+		LastModified: time.Unix(1500000000, 0),
+	}, info)
+
+	partition, err := service.TableMetadata("results$20240101")
+	assert.Nil(err)
+	assert.Equal(info, partition)
+
+	info, err = service.TableMetadata("empty")
+	assert.Nil(err)
+	assert.True(info.LastModified.IsZero())
+
+	_, err = service.TableMetadata("other.results")
+	assert.Equal(ErrTableNotFound, err)
+
+	_, err = service.TableMetadata("a.b.c.d")
+	assert.NotNil(err)
+
+	_, err = service.TableMetadata("results$20240230")
+	assert.NotNil(err)
+	assert.Equal(4, backend.calls["GetTable"])
+}
+
+func TestServiceListTables(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(0)
+	backend.tables = map[string]*bigquery.Table{
+		"samples.a":     {},
+		"samples.b":     {},
+		"samples.c":     {},
+		"other.results": {},
+	}
+	service := newFakeService(backend, Config{})
+
+	ids, err := service.ListTables()
+	assert.Nil(err)
+	assert.Equal([]string{"a", "b", "c"}, ids)
+	assert.Equal(2, backend.calls["ListTables"])
+
+	ids, err = service.WithDataset("other").ListTables()
+	assert.Nil(err)
+	assert.Equal([]string{"results"}, ids)
+}
+
+func TestServiceQueryToTableExpiration(t *testing.T) {
+	assert := assert.New(t)
+	backend := newFakeBackend(5)
+	backend.tables = map[string]*bigquery.Table{"samples.results": {}}
+	service := newFakeService(backend, Config{})
+
+	before := time.Now().Add(time.Hour).UnixNano() / int64(time.Millisecond)
+	q, err := service.QueryToTable(testQuery, "results", DestinationExpiration(time.Hour))
+	assert.Nil(err)
+	assert.NotNil(q)
+	assert.True(backend.tables["samples.results"].ExpirationTime >= before)
+
+	q, err = service.QueryToTable(testQuery, "missing", DestinationExpiration(time.Hour))
+	assert.NotNil(q)
+	assert.IsType(&ExpirationError{}, err)
+	assert.Equal("missing", err.(*ExpirationError).Table.TableId)
+
+	_, err = service.QueryToTable(testQuery, "missing")
+	assert.Nil(err)
+	assert.Equal(2, backend.calls["PatchTable"])
+}
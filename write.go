@@ -0,0 +1,203 @@
+package bigq
+
+import (
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"google.golang.org/api/bigquery/v2"
+)
+
+// WriteCSV writes the rows of the query resultset that have not been
+// retrieved yet to the given writer as CSV, fetching the pages as they are
+// needed, preceded by a header row with the names of the columns. The values
+// are converted the same way they are converted by Rows and written in
+// their canonical format, with TIMESTAMP values in RFC 3339 format. RECORD
+// and REPEATED values are written as JSON. NULL values are written as empty
+// cells. Using this method sets the query in "all" mode, that is, the
+// NextPage method can't be used after using WriteCSV.
+func (q *query) WriteCSV(w io.Writer) error {
+	rows := q.All()
+	cw := csv.NewWriter(w)
+
+	row, ok := rows.Next()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	header := make([]string, len(q.schema))
+	for i, field := range q.schema {
+		header[i] = field.Name
+	}
+
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+
+	for ok {
+		values, err := exportRow(q.schema, row)
+		if err != nil {
+			return err
+		}
+
+		record := make([]string, len(values))
+		for i, v := range values {
+			if record[i], err = csvCell(v); err != nil {
+				return err
+			}
+		}
+
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+		row, ok = rows.Next()
+	}
+
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the rows of the query resultset that have not been
+// retrieved yet to the given writer as newline-delimited JSON, fetching the
+// pages as they are needed, with every row as a JSON object of column names
+// to their values. The values are converted the same way as in WriteCSV,
+// but keeping their types, that is, numbers and booleans are written as
+// JSON numbers and booleans, and RECORD and REPEATED values as JSON
+// objects and arrays. Using this method sets the query in "all" mode, that
+// is, the NextPage method can't be used after using WriteJSON.
+func (q *query) WriteJSON(w io.Writer) error {
+	rows := q.All()
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+
+	for {
+		row, ok := rows.Next()
+		if !ok {
+			return rows.Err()
+		}
+
+		values, err := exportRow(q.schema, row)
+		if err != nil {
+			return err
+		}
+
+		obj := make(map[string]interface{}, len(values))
+		for i, v := range values {
+			obj[q.schema[i].Name] = v
+		}
+
+		if err := enc.Encode(obj); err != nil {
+			return err
+		}
+	}
+}
+
+// exportRow returns the values of the columns of the given row, converted
+// with exportValue, in the order of the schema.
+func exportRow(schema []*bigquery.TableFieldSchema, row []interface{}) ([]interface{}, error) {
+	if len(schema) < len(row) {
+		return nil, fmt.Errorf("the schema has %d fields but the row has %d columns", len(schema), len(row))
+	}
+
+	values := make([]interface{}, len(row))
+	for i, cell := range row {
+		v, err := convertValue(schema[i], cell)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = exportValue(schema[i], v)
+	}
+	return values, nil
+}
+
+// exportValue returns the given value of the given field, converted with
+// convertValue, as a value that can be encoded as JSON keeping its type.
+// TIMESTAMP values are returned in RFC 3339 format and DATE, DATETIME and
+// TIME values in their canonical format. NUMERIC and BIGNUMERIC values are
+// returned as JSON numbers with all their digits. Floats that can't be
+// represented in JSON, such as NaN, are returned as strings.
+func exportValue(field *bigquery.TableFieldSchema, v interface{}) interface{} {
+	switch v := v.(type) {
+	case []interface{}:
+		item := *field
+		item.Mode = ""
+
+		values := make([]interface{}, len(v))
+		for i, it := range v {
+			values[i] = exportValue(&item, it)
+		}
+		return values
+	case []map[string]interface{}:
+		item := *field
+		item.Mode = ""
+
+		values := make([]interface{}, len(v))
+		for i, it := range v {
+			values[i] = exportValue(&item, it)
+		}
+		return values
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for _, f := range field.Fields {
+			m[f.Name] = exportValue(f, v[f.Name])
+		}
+		return m
+	case *big.Rat:
+		return json.Number(formatNumeric(v))
+	case []byte:
+		return base64.StdEncoding.EncodeToString(v)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+	case time.Time:
+		switch field.Type {
+		case "DATE":
+			return v.Format(dateFormat)
+		case "DATETIME":
+			return v.Format("2006-01-02T15:04:05.999999")
+		case "TIME":
+			return v.Format("15:04:05.999999")
+		default:
+			return v.Format(time.RFC3339Nano)
+		}
+	}
+	return v
+}
+
+// formatNumeric formats the given NUMERIC or BIGNUMERIC value as a decimal
+// number with all its digits, which are at most 38 after the decimal point.
+func formatNumeric(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	return strings.TrimRight(r.FloatString(38), "0")
+}
+
+// csvCell returns the given value, converted with exportValue, as the text
+// of a CSV cell.
+func csvCell(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return v, nil
+	case json.Number:
+		return string(v), nil
+	case []interface{}, map[string]interface{}:
+		b, err := json.Marshal(v)
+		return string(b), err
+	}
+	return fmt.Sprint(v), nil
+}
@@ -0,0 +1,86 @@
+package bigq
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/bigquery/v2"
+)
+
+var writeSchema = []*bigquery.TableFieldSchema{
+	{Name: "word", Type: "STRING"},
+	{Name: "count", Type: "INTEGER"},
+	{Name: "ratio", Type: "NUMERIC"},
+	{Name: "ts", Type: "TIMESTAMP"},
+	{Name: "day", Type: "DATE"},
+	{Name: "tags", Type: "STRING", Mode: "REPEATED"},
+	{Name: "pos", Type: "RECORD", Fields: []*bigquery.TableFieldSchema{
+		{Name: "line", Type: "INTEGER"},
+	}},
+	{Name: "data", Type: "BYTES"},
+}
+
+func newWriteQuery() Query {
+	return newQuery(context.Background(), nil, &bigquery.GetQueryResultsResponse{
+		JobReference: &bigquery.JobReference{JobId: "foo"},
+		Schema:       &bigquery.TableSchema{Fields: writeSchema},
+		Rows: []*bigquery.TableRow{
+			{F: []*bigquery.TableCell{
+				{V: "zeal, \"zed\""},
+				{V: "5"},
+				{V: "0.125"},
+				{V: "1.458147217123456E9"},
+				{V: "2016-03-16"},
+				{V: []interface{}{map[string]interface{}{"v": "a"}, map[string]interface{}{"v": "b"}}},
+				{V: map[string]interface{}{"f": []interface{}{map[string]interface{}{"v": "3"}}}},
+				{V: "AAE="},
+			}},
+			{F: []*bigquery.TableCell{
+				{V: "zed"},
+				{V: nil},
+				{V: "2"},
+				{V: nil},
+				{V: nil},
+				{V: []interface{}{}},
+				{V: nil},
+				{V: nil},
+			}},
+		},
+		TotalRows: 2,
+	}, "go-bigq", 0, 0)
+}
+
+func TestQueryWriteCSV(t *testing.T) {
+	assert := assert.New(t)
+	q := newWriteQuery()
+
+	var buf bytes.Buffer
+	assert.Nil(q.WriteCSV(&buf))
+	assert.Equal(
+		"word,count,ratio,ts,day,tags,pos,data\n"+
+			"\"zeal, \"\"zed\"\"\",5,0.125,2016-03-16T16:53:37.123456Z,2016-03-16,\"[\"\"a\"\",\"\"b\"\"]\",\"{\"\"line\"\":3}\",AAE=\n"+
+			"zed,,2,,,[],,\n",
+		buf.String(),
+	)
+
+	_, err := q.NextPage()
+	assert.Equal(errAlreadyReading, err)
+}
+
+func TestQueryWriteJSON(t *testing.T) {
+	assert := assert.New(t)
+	q := newWriteQuery()
+
+	var buf bytes.Buffer
+	assert.Nil(q.WriteJSON(&buf))
+	assert.Equal(
+		`{"count":5,"data":"AAE=","day":"2016-03-16","pos":{"line":3},"ratio":0.125,"tags":["a","b"],"ts":"2016-03-16T16:53:37.123456Z","word":"zeal, \"zed\""}`+"\n"+
+			`{"count":null,"data":null,"day":null,"pos":null,"ratio":2,"tags":[],"ts":null,"word":"zed"}`+"\n",
+		buf.String(),
+	)
+
+	_, err := q.NextPage()
+	assert.Equal(errAlreadyReading, err)
+}